@@ -0,0 +1,292 @@
+package deb
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// FileChanges is a file-level summary of changes between two builds of a package
+type FileChanges struct {
+	added    []string
+	removed  []string
+	modified []string
+}
+
+// NewFileChanges constructor
+func NewFileChanges() *FileChanges {
+	fc := new(FileChanges)
+	fc.added = make([]string, 0)
+	fc.removed = make([]string, 0)
+	fc.modified = make([]string, 0)
+	return fc
+}
+
+// Added files, which are only in the new package
+func (fc *FileChanges) Added() []string {
+	return fc.added
+}
+
+// Removed files, which are only in the old package
+func (fc *FileChanges) Removed() []string {
+	return fc.removed
+}
+
+// Modified files, which content, mode, ownership or link target differs
+func (fc *FileChanges) Modified() []string {
+	return fc.modified
+}
+
+// PackageChange describes a package between two sets of packages
+type PackageChange struct {
+	name       string
+	arch       string
+	oldVersion string
+	newVersion string
+	files      *FileChanges
+}
+
+// Name of the package
+func (pc *PackageChange) Name() string {
+	return pc.name
+}
+
+// Architecture of the package
+func (pc *PackageChange) Architecture() string {
+	return pc.arch
+}
+
+// OldVersion returns version of the package in the old set, empty if the package is new
+func (pc *PackageChange) OldVersion() string {
+	return pc.oldVersion
+}
+
+// NewVersion returns version of the package in the new set, empty if the package was removed
+func (pc *PackageChange) NewVersion() string {
+	return pc.newVersion
+}
+
+// Files returns file-level changes. It is nil if the package is only in one of the sets,
+// or if file lists are not available (packages were read without data).
+func (pc *PackageChange) Files() *FileChanges {
+	return pc.files
+}
+
+// CompareReport is an upgrade report between two sets of packages
+type CompareReport struct {
+	added      []PackageChange
+	removed    []PackageChange
+	upgraded   []PackageChange
+	downgraded []PackageChange
+	unchanged  []PackageChange
+	warnings   []string
+}
+
+// NewCompareReport constructor
+func NewCompareReport() *CompareReport {
+	cr := new(CompareReport)
+	cr.added = make([]PackageChange, 0)
+	cr.removed = make([]PackageChange, 0)
+	cr.upgraded = make([]PackageChange, 0)
+	cr.downgraded = make([]PackageChange, 0)
+	cr.unchanged = make([]PackageChange, 0)
+	cr.warnings = make([]string, 0)
+	return cr
+}
+
+// Added returns packages, which are only in the new set
+func (cr *CompareReport) Added() []PackageChange {
+	return cr.added
+}
+
+// Removed returns packages, which are only in the old set
+func (cr *CompareReport) Removed() []PackageChange {
+	return cr.removed
+}
+
+// Upgraded returns packages with higher version in the new set
+func (cr *CompareReport) Upgraded() []PackageChange {
+	return cr.upgraded
+}
+
+// Downgraded returns packages with lower version in the new set
+func (cr *CompareReport) Downgraded() []PackageChange {
+	return cr.downgraded
+}
+
+// Unchanged returns packages with the same version in both sets.
+// Their files still might differ, if they were rebuilt.
+func (cr *CompareReport) Unchanged() []PackageChange {
+	return cr.unchanged
+}
+
+// Warnings returns packages, which could not be read and are missing in the report
+func (cr *CompareReport) Warnings() []string {
+	return cr.warnings
+}
+
+// Key of a package in a set: name and architecture
+func packageKey(cf *ControlFile) string {
+	return cf.Package() + ":" + cf.Architecture()
+}
+
+// Index packages by their name and architecture, keeping the highest version only
+func indexPackages(packages []*PackageFile) map[string]*PackageFile {
+	index := make(map[string]*PackageFile)
+	for _, p := range packages {
+		key := packageKey(p.control)
+		if known, ok := index[key]; !ok || CompareVersions(p.control.Version(), known.control.Version()) > 0 {
+			index[key] = p
+		}
+	}
+	return index
+}
+
+// ComparePackages compares two sets of packages and makes an upgrade report.
+// File-level changes are only available, if the packages were read with their data (not MetaOnly).
+func ComparePackages(oldPackages []*PackageFile, newPackages []*PackageFile) *CompareReport {
+	report := NewCompareReport()
+	oldIndex, newIndex := indexPackages(oldPackages), indexPackages(newPackages)
+
+	keys := make([]string, 0, len(oldIndex)+len(newIndex))
+	for key := range oldIndex {
+		keys = append(keys, key)
+	}
+	for key := range newIndex {
+		if _, ok := oldIndex[key]; !ok {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		oldPkg, newPkg := oldIndex[key], newIndex[key]
+		pc := PackageChange{}
+		if oldPkg != nil {
+			pc.name, pc.arch, pc.oldVersion = oldPkg.control.Package(), oldPkg.control.Architecture(), oldPkg.control.Version()
+		}
+		if newPkg != nil {
+			pc.name, pc.arch, pc.newVersion = newPkg.control.Package(), newPkg.control.Architecture(), newPkg.control.Version()
+		}
+
+		switch {
+		case oldPkg == nil:
+			report.added = append(report.added, pc)
+			continue
+		case newPkg == nil:
+			report.removed = append(report.removed, pc)
+			continue
+		}
+
+		pc.files = compareFiles(oldPkg, newPkg)
+		switch CompareVersions(pc.oldVersion, pc.newVersion) {
+		case -1:
+			report.upgraded = append(report.upgraded, pc)
+		case 1:
+			report.downgraded = append(report.downgraded, pc)
+		default:
+			report.unchanged = append(report.unchanged, pc)
+		}
+	}
+
+	return report
+}
+
+// Digest of a file in a package: calculated checksum, if any, otherwise the one from md5sums
+func (c *PackageFile) fileDigest(name string) string {
+	if sum, ok := c.fileCalculatedChecksums[name]; ok {
+		return sum
+	}
+	return c.GetFileMd5Sums(name)
+}
+
+// Compare file lists of two packages, nil if any of them has no files read
+func compareFiles(oldPkg *PackageFile, newPkg *PackageFile) *FileChanges {
+	if len(oldPkg.files) == 0 || len(newPkg.files) == 0 {
+		return nil
+	}
+
+	fc := NewFileChanges()
+	oldFiles := make(map[string]FileInfo)
+	for _, fi := range oldPkg.files {
+		oldFiles[fi.name] = fi
+	}
+
+	sameHash := oldPkg.checksumHash == newPkg.checksumHash
+	for _, fi := range newPkg.files {
+		old, ok := oldFiles[fi.name]
+		if !ok {
+			fc.added = append(fc.added, fi.name)
+			continue
+		}
+		delete(oldFiles, fi.name)
+
+		modified := old.mode != fi.mode || old.size != fi.size || old.linkname != fi.linkname ||
+			old.owner != fi.owner || old.group != fi.group
+		if !modified && sameHash && fi.mode.IsRegular() {
+			modified = oldPkg.fileDigest(fi.name) != newPkg.fileDigest(fi.name)
+		}
+		if modified {
+			fc.modified = append(fc.modified, fi.name)
+		}
+	}
+
+	for name := range oldFiles {
+		fc.removed = append(fc.removed, name)
+	}
+	sort.Strings(fc.added)
+	sort.Strings(fc.removed)
+	sort.Strings(fc.modified)
+
+	return fc
+}
+
+// Read all Debian packages under the directory, recursively.
+// Packages that cannot be read are returned as warnings.
+func openPackageDir(dir string, opts *PackageOptions) ([]*PackageFile, []string, error) {
+	packages := make([]*PackageFile, 0)
+	warnings := make([]string, 0)
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() || !strings.HasSuffix(info.Name(), ".deb") {
+			return nil
+		}
+		p, err := OpenPackageFile(path, opts)
+		if err != nil {
+			warnings = append(warnings, fmt.Sprintf("cannot read package %s: %v", path, err))
+			return nil
+		}
+		packages = append(packages, p)
+		return nil
+	})
+
+	return packages, warnings, err
+}
+
+// CompareDirectories compares Debian packages found in two directories (recursively),
+// such as two snapshots of a repository pool. Packages that cannot be read are skipped
+// and listed in the report warnings.
+func CompareDirectories(oldDir string, newDir string, opts *PackageOptions) (*CompareReport, error) {
+	oldPackages, oldWarnings, err := openPackageDir(oldDir, opts)
+	if err != nil {
+		return nil, err
+	}
+	newPackages, newWarnings, err := openPackageDir(newDir, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	report := ComparePackages(oldPackages, newPackages)
+	report.warnings = append(append(report.warnings, oldWarnings...), newWarnings...)
+	return report, nil
+}
+
+// CompareIndexes compares two repository suites by their Packages indexes.
+// Indexes carry no file lists, so only package-level changes are reported.
+func CompareIndexes(oldIndex []byte, newIndex []byte) *CompareReport {
+	return ComparePackages(ParsePackagesIndex(oldIndex), ParsePackagesIndex(newIndex))
+}
@@ -7,11 +7,15 @@ import (
 
 type CfgFilesFile struct {
 	names []string
+	flags map[string][]string // Flags of the conffiles, e.g. "remove-on-upgrade"
+	lines []string            // Lines as in the conffiles file
 }
 
 func NewCfgFilesFiles() *CfgFilesFile {
 	cfg := new(CfgFilesFile)
 	cfg.names = make([]string, 0)
+	cfg.flags = make(map[string][]string)
+	cfg.lines = make([]string, 0)
 	return cfg
 }
 
@@ -21,13 +25,34 @@ func (cfg *CfgFilesFile) parse(data []byte) error {
 	for scn.Scan() {
 		line = strings.TrimSpace(scn.Text())
 		if line != "" && !strings.HasPrefix(line, "#") {
+			cfg.lines = append(cfg.lines, line)
+
+			// Flags precede the absolute path, e.g. "remove-on-upgrade /etc/foo.conf"
+			flags := make([]string, 0)
+			for !strings.HasPrefix(line, "/") {
+				fe := strings.SplitN(line, " ", 2)
+				if len(fe) != 2 {
+					break
+				}
+				flags = append(flags, fe[0])
+				line = strings.TrimSpace(fe[1])
+			}
 			cfg.names = append(cfg.names, line)
+			if len(flags) > 0 {
+				cfg.flags[line] = flags
+			}
 		}
 	}
 
 	return nil
 }
 
+// Names returns paths of the configuration files, without flags
 func (cfg *CfgFilesFile) Names() []string {
 	return cfg.names
 }
+
+// Flags of the configuration file, e.g. "remove-on-upgrade"
+func (cfg *CfgFilesFile) Flags(name string) []string {
+	return cfg.flags[name]
+}
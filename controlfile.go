@@ -1,7 +1,10 @@
 package deb
 
 import (
+	"bufio"
+	"bytes"
 	"errors"
+	"fmt"
 	"regexp"
 	"strconv"
 	"strings"
@@ -19,6 +22,11 @@ type ControlFile struct {
 	oe            string
 	installedSize int
 
+	// Vendor-specific fields (Debian and Ubuntu archive metadata)
+	origin                 string
+	bugs                   string
+	phasedUpdatePercentage int
+
 	// Canonical names of control file fields that are folded fields.
 	// They contain a comma separated list of package names with optional version specifications.
 	breaks     []string
@@ -30,6 +38,8 @@ type ControlFile struct {
 	recommends []string
 	replaces   []string
 	suggests   []string
+	task       []string          // Ubuntu's tasksel membership
+	relations  map[string]string // Raw values of the relation fields, see Relations()
 
 	section            string
 	priority           string
@@ -37,17 +47,29 @@ type ControlFile struct {
 	description        string
 	summary            string // This is not a standard field of Dpkg and it basically contains only a first line of description.
 	originalMaintainer string
+	status             string // Only in the dpkg status database
+
+	raw []byte // Control file as it was in the package
 }
 
 func NewControlFile() *ControlFile {
 	cf := new(ControlFile)
 	cf.depends = make([]string, 0)
 	cf.suggests = make([]string, 0)
+	cf.task = make([]string, 0)
+	cf.relations = make(map[string]string)
 	cf.multiArch = ""
+	cf.phasedUpdatePercentage = -1
 
 	return cf
 }
 
+// Canonical (lowercase) names of the relation fields of a binary package
+var relationFields = []string{"depends", "pre-depends", "suggests", "breaks", "enhances", "conflicts", "provides", "recommends", "replaces"}
+
+// Canonical names of the fields with integer values. Other numeric-looking values (e.g. Version: 010) are kept as strings.
+var intFields = []string{"installed-size", "phased-update-percentage"}
+
 // Check if a string is in the array
 func in(a string, list []string) bool {
 	for _, b := range list {
@@ -58,11 +80,63 @@ func in(a string, list []string) bool {
 	return false
 }
 
-// Add to the field
+// A field of a control paragraph with its value kept as is,
+// i.e. continuation lines are still prefixed with a space.
+type controlField struct {
+	name  string
+	value string
+}
+
+// Split raw control paragraph into fields, preserving their order. Comments and OpenPGP
+// clearsign armor (of .dsc files) are skipped. Malformed lines are skipped as well,
+// the first one is returned as an error.
+func splitControlFields(data []byte) ([]controlField, error) {
+	var malformed error
+	fields := make([]controlField, 0)
+	inArmorHeader, inSignature := false, false
+	scn := bufio.NewScanner(bytes.NewReader(data))
+	for scn.Scan() {
+		line := strings.TrimRight(scn.Text(), "\r")
+		switch {
+		case strings.HasPrefix(line, "-----BEGIN PGP SIGNED MESSAGE"):
+			inArmorHeader = true // "Hash: ..." lines up to the first empty line
+		case inArmorHeader:
+			inArmorHeader = strings.TrimSpace(line) != ""
+		case strings.HasPrefix(line, "-----BEGIN PGP SIGNATURE"):
+			inSignature = true
+		case strings.HasPrefix(line, "-----END PGP SIGNATURE"):
+			inSignature = false
+		case inSignature, strings.HasPrefix(line, "#"), strings.TrimSpace(line) == "":
+			continue
+		case strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t"):
+			if len(fields) > 0 {
+				fields[len(fields)-1].value += "\n" + line
+			}
+		default:
+			namedata := strings.SplitN(line, ":", 2)
+			if len(namedata) != 2 {
+				if malformed == nil {
+					malformed = fmt.Errorf("malformed control line: %s", line)
+				}
+				continue
+			}
+			fields = append(fields, controlField{name: strings.TrimSpace(namedata[0]), value: strings.TrimSpace(namedata[1])})
+		}
+	}
+	if err := scn.Err(); err != nil {
+		return fields, err
+	}
+	return fields, malformed
+}
+
+// Add continuation line to the field
 func (cf *ControlFile) addToField(name string, data string) {
-	switch strings.ToLower(name) {
-	case "description":
+	name = strings.ToLower(strings.TrimSpace(name))
+	switch {
+	case name == "description":
 		cf.description += " " + strings.TrimSpace(data)
+	case name == "task" || in(name, relationFields):
+		cf.setFoldedField(name, strings.TrimSpace(data))
 	}
 }
 
@@ -73,9 +147,9 @@ func (cf *ControlFile) setField(data ...string) error {
 	}
 	name, value := strings.ToLower(strings.TrimSpace(data[0])), strings.TrimSpace(data[1])
 	i, err := strconv.Atoi(value)
-	if in(name, []string{"depends", "predepends", "suggests", "breaks", "enhances", "conflicts", "provides", "recommends", "replaces"}) {
+	if name == "task" || in(name, relationFields) {
 		cf.setFoldedField(name, value)
-	} else if err == nil {
+	} else if err == nil && in(name, intFields) {
 		cf.setIntField(name, i)
 	} else {
 		cf.setStringField(name, value)
@@ -90,7 +164,7 @@ func (cf *ControlFile) setFoldedField(name string, data string) {
 	switch name {
 	case "depends":
 		ptr = &cf.depends
-	case "predepends":
+	case "pre-depends":
 		ptr = &cf.predepends
 	case "suggests":
 		ptr = &cf.suggests
@@ -106,6 +180,8 @@ func (cf *ControlFile) setFoldedField(name string, data string) {
 		ptr = &cf.recommends
 	case "replaces":
 		ptr = &cf.replaces
+	case "task":
+		ptr = &cf.task
 	default:
 		ptr = nil
 		logger.Println("@@ missing folded data for:", name)
@@ -114,6 +190,12 @@ func (cf *ControlFile) setFoldedField(name string, data string) {
 
 	// Try to make sense of that messy pile of many ways they call "standard"
 	if ptr != nil {
+		if name != "task" && cf.relations[name] != "" {
+			cf.relations[name] += "\n" + data // Continuation line
+		} else if name != "task" {
+			cf.relations[name] = data
+		}
+
 		var vals []string
 		if strings.Contains(data, ",") || strings.Contains(data, "|") || strings.Contains(data, "(") {
 			vals = regexp.MustCompile(`[\\,\\|]`).Split(data, -1)
@@ -134,6 +216,8 @@ func (cf *ControlFile) setIntField(name string, data int) {
 	switch name {
 	case "installed-size":
 		cf.installedSize = data
+	case "phased-update-percentage":
+		cf.phasedUpdatePercentage = data
 	}
 }
 
@@ -153,7 +237,7 @@ func (cf *ControlFile) setStringField(name string, data string) {
 		cf.section = data
 	case "priority":
 		cf.priority = data
-	case "original-maintainer":
+	case "original-maintainer", "xsbc-original-maintainer":
 		cf.originalMaintainer = data
 	case "version":
 		cf.version = data
@@ -171,6 +255,16 @@ func (cf *ControlFile) setStringField(name string, data string) {
 		cf.licence = data
 	case "oe":
 		cf.oe = data
+	case "origin":
+		cf.origin = data
+	case "bugs":
+		cf.bugs = data
+	case "status":
+		cf.status = data
+	case "filename", "size", "md5sum", "sha1", "sha256", "sha512", "description-md5":
+		// Packages index fields, see PackageFile.PackagesEntry
+	case "conffiles", "config-version":
+		// dpkg status database fields
 	default:
 		logger.Println("Field", name, "is not yet supported:")
 		logger.Println(data)
@@ -178,6 +272,11 @@ func (cf *ControlFile) setStringField(name string, data string) {
 	}
 }
 
+// Raw returns the control file data as it was found in the package
+func (cf *ControlFile) Raw() []byte {
+	return cf.raw
+}
+
 // Source
 func (cf *ControlFile) Source() string {
 	return cf.src
@@ -233,10 +332,43 @@ func (cf *ControlFile) Summary() string {
 	return cf.summary
 }
 
+// OriginalMaintainer returns the Debian maintainer of a package that was
+// taken over by a derivative (Ubuntu). XSBC-Original-Maintainer is honoured as well.
 func (cf *ControlFile) OriginalMaintainer() string {
 	return cf.originalMaintainer
 }
 
+// Status returns the dpkg status (e.g. "install ok installed"), if read from the dpkg status database
+func (cf *ControlFile) Status() string {
+	return cf.status
+}
+
+// Homepage of the upstream project
+func (cf *ControlFile) Homepage() string {
+	return cf.homepage
+}
+
+// Origin returns the vendor name the package comes from (e.g. "Ubuntu")
+func (cf *ControlFile) Origin() string {
+	return cf.origin
+}
+
+// Bugs returns the bug tracking system URI, e.g. "debbugs://bugs.debian.org"
+func (cf *ControlFile) Bugs() string {
+	return cf.bugs
+}
+
+// PhasedUpdatePercentage returns Ubuntu's phased update percentage (0-100).
+// It returns -1 if the field is not present, which means the update is fully released.
+func (cf *ControlFile) PhasedUpdatePercentage() int {
+	return cf.phasedUpdatePercentage
+}
+
+// Task returns the list of Ubuntu tasks the package belongs to
+func (cf *ControlFile) Task() []string {
+	return cf.task
+}
+
 func (cf *ControlFile) Depends() []string {
 	return cf.depends
 }
@@ -272,3 +404,13 @@ func (cf *ControlFile) Enhances() []string {
 func (cf *ControlFile) Predepends() []string {
 	return cf.predepends
 }
+
+// Relations parses a relation field (e.g. "depends", "breaks") into groups of alternatives.
+// Unlike the flat lists above, it keeps version restrictions and "|" alternatives apart.
+func (cf *ControlFile) Relations(name string) ([][]Relation, error) {
+	name = strings.ToLower(name)
+	if !in(name, relationFields) {
+		return nil, fmt.Errorf("%s is not a relation field", name)
+	}
+	return ParseRelations(cf.relations[name])
+}
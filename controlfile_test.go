@@ -0,0 +1,33 @@
+package deb
+
+import "testing"
+
+func TestControlFileFields(t *testing.T) {
+	p := NewPackageFile()
+	p.parseControlFile([]byte("Package: foo\nVersion: 010\nArchitecture: amd64\nInstalled-Size: 1024\nPhased-Update-Percentage: 30\nOrigin: Ubuntu\nBugs: https://bugs.launchpad.net/ubuntu/+filebug\n"))
+	cf := p.ControlFile()
+
+	for _, tt := range []struct {
+		name     string
+		got      interface{}
+		expected interface{}
+	}{
+		{"Package", cf.Package(), "foo"},
+		{"Version", cf.Version(), "010"},
+		{"Architecture", cf.Architecture(), "amd64"},
+		{"InstalledSize", cf.InstalledSize(), 1024},
+		{"PhasedUpdatePercentage", cf.PhasedUpdatePercentage(), 30},
+		{"Origin", cf.Origin(), "Ubuntu"},
+		{"Bugs", cf.Bugs(), "https://bugs.launchpad.net/ubuntu/+filebug"},
+	} {
+		if tt.got != tt.expected {
+			t.Errorf("%s() = %v, expected %v", tt.name, tt.got, tt.expected)
+		}
+	}
+
+	p = NewPackageFile()
+	p.parseControlFile([]byte("Package: bar\nVersion: +1\n"))
+	if p.ControlFile().Version() != "+1" || p.ControlFile().PhasedUpdatePercentage() != -1 {
+		t.Errorf("unexpected Version %q and PhasedUpdatePercentage %d", p.ControlFile().Version(), p.ControlFile().PhasedUpdatePercentage())
+	}
+}
@@ -0,0 +1,148 @@
+package deb
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// Package and architecture names as allowed by dpkg. They become file names in the dpkg database.
+var (
+	dpkgPackageName = regexp.MustCompile(`^[a-z0-9][a-z0-9+.-]+$`)
+	dpkgArchName    = regexp.MustCompile(`^[a-z0-9][a-z0-9-]*$`)
+)
+
+// DpkgInfoFile is a file of the dpkg database, as dpkg would put it into /var/lib/dpkg/info
+type DpkgInfoFile struct {
+	name string
+	mode os.FileMode
+	data []byte
+}
+
+// Name of the file, e.g. "foo.list" or "libfoo1:amd64.postinst"
+func (dif *DpkgInfoFile) Name() string {
+	return dif.name
+}
+
+// Mode of the file, maintainer scripts are executable
+func (dif *DpkgInfoFile) Mode() os.FileMode {
+	return dif.mode
+}
+
+// Data of the file
+func (dif *DpkgInfoFile) Data() []byte {
+	return dif.data
+}
+
+// Base name of the files in the dpkg database. Multi-Arch: same packages are qualified with architecture.
+func (c *PackageFile) dpkgInfoName() string {
+	if c.control.MultiArch() == "same" {
+		return c.control.Package() + ":" + c.control.Architecture()
+	}
+	return c.control.Package()
+}
+
+// DpkgList returns the .list file of the dpkg database: all paths the package installs
+func (c *PackageFile) DpkgList() string {
+	var list strings.Builder
+	for _, fi := range c.files {
+		name := strings.TrimSuffix(installPath(fi.name), "/")
+		if name == "" {
+			name = "/."
+		}
+		list.WriteString(name + "\n")
+	}
+	return list.String()
+}
+
+// DpkgMd5sums returns the .md5sums file of the dpkg database. These are taken from the md5sums
+// of the package, or from the calculated checksums if the package has none and they are MD5.
+func (c *PackageFile) DpkgMd5sums() string {
+	var md5sums strings.Builder
+	conffiles := c.conffiles.Names()
+	for _, fi := range c.files {
+		name := strings.TrimPrefix(installPath(fi.name), "/")
+		sum := c.GetFileMd5Sums(name)
+		if sum == "" && len(c.fileMd5Checksums) == 0 && c.checksumHash == HASH_MD5 && !in("/"+name, conffiles) {
+			sum = c.fileCalculatedChecksums[fi.name]
+		}
+		if sum != "" {
+			md5sums.WriteString(sum + "  " + name + "\n")
+		}
+	}
+	return md5sums.String()
+}
+
+// DpkgConffiles returns the .conffiles file of the dpkg database
+func (c *PackageFile) DpkgConffiles() string {
+	var conffiles strings.Builder
+	for _, line := range c.conffiles.lines {
+		conffiles.WriteString(line + "\n") // Flags are kept, as dpkg does
+	}
+	return conffiles.String()
+}
+
+// DpkgInfo returns the files that dpkg would put into its database (/var/lib/dpkg/info)
+// when installing the package: .list, .md5sums, .conffiles and maintainer scripts.
+// The package has to be read with its data and its maintainer scripts must not be streamed away.
+func (c *PackageFile) DpkgInfo() ([]DpkgInfoFile, error) {
+	if c.control.Package() == "" {
+		return nil, fmt.Errorf("package has no name")
+	}
+	if !dpkgPackageName.MatchString(c.control.Package()) {
+		return nil, fmt.Errorf("invalid package name '%s'", c.control.Package())
+	}
+	if c.control.MultiArch() == "same" && !dpkgArchName.MatchString(c.control.Architecture()) {
+		return nil, fmt.Errorf("invalid architecture '%s' of package %s", c.control.Architecture(), c.control.Package())
+	}
+	if len(c.files) == 0 {
+		return nil, fmt.Errorf("package %s was read without data, file list is not available", c.control.Package())
+	}
+
+	base := c.dpkgInfoName()
+	info := []DpkgInfoFile{{name: base + ".list", mode: 0644, data: []byte(c.DpkgList())}}
+	if md5sums := c.DpkgMd5sums(); md5sums != "" {
+		info = append(info, DpkgInfoFile{name: base + ".md5sums", mode: 0644, data: []byte(md5sums)})
+	}
+	if len(c.conffiles.Names()) > 0 {
+		info = append(info, DpkgInfoFile{name: base + ".conffiles", mode: 0644, data: []byte(c.DpkgConffiles())})
+	}
+
+	for _, script := range c.scripts {
+		var body string
+		switch script.name {
+		case "preinst":
+			body = c.preinst
+		case "postinst":
+			body = c.postinst
+		case "prerm":
+			body = c.prerm
+		case "postrm":
+			body = c.postrm
+		}
+		if int64(len(body)) != script.size {
+			return nil, fmt.Errorf("body of %s script is not available, it was streamed to a sink", script.name)
+		}
+		info = append(info, DpkgInfoFile{name: base + "." + script.name, mode: 0755, data: []byte(body)})
+	}
+
+	return info, nil
+}
+
+// WriteDpkgInfo writes the dpkg database files of the package into a directory,
+// usually /var/lib/dpkg/info of the image being built.
+func (c *PackageFile) WriteDpkgInfo(dir string) error {
+	info, err := c.DpkgInfo()
+	if err != nil {
+		return err
+	}
+	for _, dif := range info {
+		if err := ioutil.WriteFile(filepath.Join(dir, dif.name), dif.data, dif.mode); err != nil {
+			return err
+		}
+	}
+	return nil
+}
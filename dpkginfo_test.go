@@ -0,0 +1,102 @@
+package deb
+
+import (
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestDpkgInfo(t *testing.T) {
+	postinst := "#!/bin/sh\nset -e\n"
+	data := testDeb(
+		testControl("Multi-Arch: same\n",
+			testEntry{name: "./conffiles", body: "/etc/foo.conf\nremove-on-upgrade /etc/foo.old\n"},
+			testEntry{name: "./postinst", body: postinst, mode: 0755},
+		),
+		[]testEntry{
+			{name: "./"}, {name: "./etc/"}, {name: "./etc/foo.conf", body: "foo=1\n"}, {name: "./etc/foo.old", body: "old\n"},
+			{name: "./usr/"}, {name: "./usr/bin/"}, {name: "./usr/bin/foo", body: "foo\n", mode: 0755}, {name: "./usr/bin/bar", link: "foo"},
+		},
+	)
+	p, err := testRead(data, DefaultPackageOptions)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(p.ConffilesFile().Names(), []string{"/etc/foo.conf", "/etc/foo.old"}) {
+		t.Errorf("unexpected conffiles: %q", p.ConffilesFile().Names())
+	}
+	if !reflect.DeepEqual(p.ConffilesFile().Flags("/etc/foo.old"), []string{"remove-on-upgrade"}) || p.ConffilesFile().Flags("/etc/foo.conf") != nil {
+		t.Errorf("unexpected conffile flags: %q %q", p.ConffilesFile().Flags("/etc/foo.old"), p.ConffilesFile().Flags("/etc/foo.conf"))
+	}
+
+	info, err := p.DpkgInfo()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []DpkgInfoFile{
+		{name: "foo:amd64.list", mode: 0644, data: []byte("/.\n/etc\n/etc/foo.conf\n/etc/foo.old\n/usr\n/usr/bin\n/usr/bin/foo\n/usr/bin/bar\n")},
+		{name: "foo:amd64.md5sums", mode: 0644, data: []byte("d3b07384d113edec49eaa6238ad5ff00  usr/bin/foo\n")},
+		{name: "foo:amd64.conffiles", mode: 0644, data: []byte("/etc/foo.conf\nremove-on-upgrade /etc/foo.old\n")},
+		{name: "foo:amd64.postinst", mode: 0755, data: []byte(postinst)},
+	}
+	if len(info) != len(expected) {
+		t.Fatalf("expected %d files, got %d", len(expected), len(info))
+	}
+	for i, dif := range info {
+		if dif.Name() != expected[i].name || dif.Mode() != expected[i].mode || string(dif.Data()) != string(expected[i].data) {
+			t.Errorf("unexpected file %s (%v):\n%s\nexpected %s (%v):\n%s", dif.Name(), dif.Mode(), dif.Data(), expected[i].name, expected[i].mode, expected[i].data)
+		}
+	}
+
+	dir, err := ioutil.TempDir("", "go-deb")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	if err := p.WriteDpkgInfo(dir); err != nil {
+		t.Fatal(err)
+	}
+	for _, dif := range expected {
+		written, err := ioutil.ReadFile(filepath.Join(dir, dif.name))
+		if err != nil || string(written) != string(dif.data) {
+			t.Errorf("unexpected written file %s: %q %v", dif.name, written, err)
+		}
+	}
+}
+
+func TestDpkgInfoErrors(t *testing.T) {
+	files := []testEntry{{name: "./"}, {name: "./usr/"}}
+	sink := func(script *MaintainerScript, body io.Reader) error {
+		_, err := io.Copy(ioutil.Discard, body)
+		return err
+	}
+
+	for _, tt := range []struct {
+		name    string
+		control []testEntry
+		opts    *PackageOptions
+	}{
+		{"no name", []testEntry{{name: "./control", body: "Version: 1.0\n"}}, DefaultPackageOptions},
+		{"no data", testControl(""), &PackageOptions{MetaOnly: true}},
+		{"streamed script", testControl("", testEntry{name: "./postinst", body: "#!/bin/sh\n", mode: 0755}), &PackageOptions{ScriptSink: sink}},
+		{"path in name", []testEntry{{name: "./control", body: "Package: ../../etc/foo\n"}}, DefaultPackageOptions},
+		{"slash in name", []testEntry{{name: "./control", body: "Package: foo/bar\n"}}, DefaultPackageOptions},
+		{"uppercase name", []testEntry{{name: "./control", body: "Package: Foo\n"}}, DefaultPackageOptions},
+		{"path in architecture", []testEntry{{name: "./control", body: "Package: foo\nArchitecture: ../amd64\nMulti-Arch: same\n"}}, DefaultPackageOptions},
+	} {
+		p, err := testRead(testDeb(tt.control, files), tt.opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := p.DpkgInfo(); err == nil {
+			t.Errorf("%s: DpkgInfo expected to fail", tt.name)
+		}
+		if err := p.WriteDpkgInfo(os.TempDir()); err == nil {
+			t.Errorf("%s: WriteDpkgInfo expected to fail", tt.name)
+		}
+	}
+}
@@ -0,0 +1,360 @@
+package deb
+
+import (
+	"bufio"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// OpenPGP signature subpacket types, RFC 4880 5.2.3.1
+const (
+	pgpSubpacketCreationTime = 2
+	pgpSubpacketIssuer       = 16
+	pgpSubpacketFingerprint  = 33
+)
+
+// SignedFile is a package member, listed in the signed message with its digests
+type SignedFile struct {
+	name string
+	md5  string
+	sha1 string
+	size int64
+}
+
+// Name of the package member, e.g. "control.tar.gz"
+func (sf *SignedFile) Name() string {
+	return sf.name
+}
+
+// MD5 digest of the member
+func (sf *SignedFile) MD5() string {
+	return sf.md5
+}
+
+// SHA1 digest of the member
+func (sf *SignedFile) SHA1() string {
+	return sf.sha1
+}
+
+// Size of the member in bytes
+func (sf *SignedFile) Size() int64 {
+	return sf.size
+}
+
+// GpgSignature is a parsed _gpgbuilder member (as written by dpkg-sig): an OpenPGP clearsigned
+// message with digests of the package members. It is not verified, it only tells
+// who claims to have signed the package.
+type GpgSignature struct {
+	raw string
+
+	hash    string // Hash armor header of the clearsigned message
+	version string
+	signer  string
+	date    string
+	role    string
+	files   []SignedFile
+
+	sigVersion  int
+	sigType     int
+	pubKeyAlgo  int
+	hashAlgo    int
+	keyID       string
+	fingerprint string
+	created     time.Time
+}
+
+// NewGpgSignature constructor
+func NewGpgSignature() *GpgSignature {
+	gs := new(GpgSignature)
+	gs.files = make([]SignedFile, 0)
+	return gs
+}
+
+// Parse clearsigned message and the signature packet
+func (gs *GpgSignature) parse(data string) error {
+	gs.raw = data
+
+	var armor []string
+	section := "" // "header", "message", "armor", "signature"
+	scn := bufio.NewScanner(strings.NewReader(data))
+	for scn.Scan() {
+		line := strings.TrimRight(scn.Text(), "\r")
+		switch {
+		case strings.HasPrefix(line, "-----BEGIN PGP SIGNED MESSAGE"):
+			section = "header"
+		case strings.HasPrefix(line, "-----BEGIN PGP SIGNATURE"):
+			section = "armor"
+		case strings.HasPrefix(line, "-----END PGP SIGNATURE"):
+			section = ""
+		case section == "header":
+			if strings.TrimSpace(line) == "" {
+				section = "message"
+			} else if strings.HasPrefix(line, "Hash:") {
+				gs.hash = strings.TrimSpace(strings.TrimPrefix(line, "Hash:"))
+			}
+		case section == "message":
+			if err := gs.parseMessageLine(strings.TrimPrefix(line, "- ")); err != nil {
+				return err
+			}
+		case section == "armor":
+			if strings.TrimSpace(line) == "" {
+				section = "signature"
+			}
+		case section == "signature":
+			if !strings.HasPrefix(line, "=") { // Skip armor checksum
+				armor = append(armor, strings.TrimSpace(line))
+			}
+		}
+	}
+
+	if len(armor) == 0 {
+		return fmt.Errorf("no signature found")
+	}
+	packet, err := base64.StdEncoding.DecodeString(strings.Join(armor, ""))
+	if err != nil {
+		return fmt.Errorf("malformed signature armor: %v", err)
+	}
+
+	return gs.parseSignaturePacket(packet)
+}
+
+// Parse a line of the signed message: either a header field or a signed file
+func (gs *GpgSignature) parseMessageLine(line string) error {
+	if strings.TrimSpace(line) == "" {
+		return nil
+	}
+	if strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t") {
+		fe := strings.Fields(line)
+		if len(fe) != 4 {
+			return fmt.Errorf("invalid signed file line: %s", line)
+		}
+		size, err := strconv.ParseInt(fe[2], 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid size in signed file line: %s", line)
+		}
+		gs.files = append(gs.files, SignedFile{md5: fe[0], sha1: fe[1], size: size, name: fe[3]})
+		return nil
+	}
+
+	namedata := strings.SplitN(line, ":", 2)
+	if len(namedata) != 2 {
+		return fmt.Errorf("invalid signed message line: %s", line)
+	}
+	value := strings.TrimSpace(namedata[1])
+	switch strings.ToLower(namedata[0]) {
+	case "version":
+		gs.version = value
+	case "signer":
+		gs.signer = value
+	case "date":
+		gs.date = value
+	case "role":
+		gs.role = value
+	}
+	return nil
+}
+
+// Parse OpenPGP signature packet (RFC 4880 5.2) for its meta-data
+func (gs *GpgSignature) parseSignaturePacket(packet []byte) error {
+	if len(packet) < 2 || packet[0]&0x80 == 0 {
+		return fmt.Errorf("invalid OpenPGP packet")
+	}
+
+	var tag int
+	var body []byte
+	if packet[0]&0x40 != 0 { // New format
+		tag = int(packet[0] & 0x3f)
+		length, offset := 0, 2
+		switch first := int(packet[1]); {
+		case first < 192:
+			length = first
+		case first < 224 && len(packet) > 2:
+			length, offset = (first-192)<<8+int(packet[2])+192, 3
+		case first == 255 && len(packet) > 5:
+			length, offset = int(binary.BigEndian.Uint32(packet[2:6])), 6
+		default:
+			return fmt.Errorf("unsupported OpenPGP packet length")
+		}
+		if offset+length > len(packet) {
+			return fmt.Errorf("truncated OpenPGP packet")
+		}
+		body = packet[offset : offset+length]
+	} else { // Old format
+		tag = int(packet[0]&0x3c) >> 2
+		var length, offset int
+		switch packet[0] & 0x03 {
+		case 0:
+			length, offset = int(packet[1]), 2
+		case 1:
+			if len(packet) < 3 {
+				return fmt.Errorf("truncated OpenPGP packet")
+			}
+			length, offset = int(binary.BigEndian.Uint16(packet[1:3])), 3
+		case 2:
+			if len(packet) < 5 {
+				return fmt.Errorf("truncated OpenPGP packet")
+			}
+			length, offset = int(binary.BigEndian.Uint32(packet[1:5])), 5
+		default:
+			length, offset = len(packet)-1, 1
+		}
+		if offset+length > len(packet) {
+			return fmt.Errorf("truncated OpenPGP packet")
+		}
+		body = packet[offset : offset+length]
+	}
+
+	if tag != 2 {
+		return fmt.Errorf("expected OpenPGP signature packet, got tag %d", tag)
+	}
+	if len(body) < 1 {
+		return fmt.Errorf("empty OpenPGP signature packet")
+	}
+
+	gs.sigVersion = int(body[0])
+	switch gs.sigVersion {
+	case 3:
+		if len(body) < 19 {
+			return fmt.Errorf("truncated v3 signature packet")
+		}
+		gs.sigType = int(body[2])
+		gs.created = time.Unix(int64(binary.BigEndian.Uint32(body[3:7])), 0).UTC()
+		gs.keyID = fmt.Sprintf("%X", body[7:15])
+		gs.pubKeyAlgo, gs.hashAlgo = int(body[15]), int(body[16])
+	case 4, 5:
+		if len(body) < 6 {
+			return fmt.Errorf("truncated signature packet")
+		}
+		gs.sigType, gs.pubKeyAlgo, gs.hashAlgo = int(body[1]), int(body[2]), int(body[3])
+		hashedLen := int(binary.BigEndian.Uint16(body[4:6]))
+		if 6+hashedLen+2 > len(body) {
+			return fmt.Errorf("truncated signature subpackets")
+		}
+		if err := gs.parseSubpackets(body[6 : 6+hashedLen]); err != nil {
+			return err
+		}
+		rest := body[6+hashedLen:]
+		unhashedLen := int(binary.BigEndian.Uint16(rest[0:2]))
+		if 2+unhashedLen > len(rest) {
+			return fmt.Errorf("truncated signature subpackets")
+		}
+		if err := gs.parseSubpackets(rest[2 : 2+unhashedLen]); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("unsupported signature version %d", gs.sigVersion)
+	}
+
+	return nil
+}
+
+// Parse signature subpackets, picking up creation time and issuer
+func (gs *GpgSignature) parseSubpackets(data []byte) error {
+	for len(data) > 0 {
+		length, offset := int(data[0]), 1
+		switch {
+		case length >= 192 && length < 255 && len(data) > 1:
+			length, offset = (length-192)<<8+int(data[1])+192, 2
+		case length == 255 && len(data) > 4:
+			length, offset = int(binary.BigEndian.Uint32(data[1:5])), 5
+		}
+		if length < 1 || offset+length > len(data) {
+			return fmt.Errorf("malformed signature subpacket")
+		}
+		sp := data[offset : offset+length]
+		data = data[offset+length:]
+
+		switch payload := sp[1:]; sp[0] & 0x7f { // Highest bit is "critical" flag
+		case pgpSubpacketCreationTime:
+			if len(payload) == 4 {
+				gs.created = time.Unix(int64(binary.BigEndian.Uint32(payload)), 0).UTC()
+			}
+		case pgpSubpacketIssuer:
+			if len(payload) == 8 && gs.keyID == "" {
+				gs.keyID = fmt.Sprintf("%X", payload)
+			}
+		case pgpSubpacketFingerprint:
+			if len(payload) > 1 {
+				gs.fingerprint = fmt.Sprintf("%X", payload[1:])
+				if gs.keyID == "" && len(payload) == 21 { // v4 key ID is the low 64 bits of the fingerprint
+					gs.keyID = gs.fingerprint[len(gs.fingerprint)-16:]
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// Raw returns the _gpgbuilder member data
+func (gs *GpgSignature) Raw() string {
+	return gs.raw
+}
+
+// Hash returns the hash algorithm from the clearsigned message header, e.g. "SHA256"
+func (gs *GpgSignature) Hash() string {
+	return gs.hash
+}
+
+// Version of the signed message format
+func (gs *GpgSignature) Version() string {
+	return gs.version
+}
+
+// Signer as claimed in the signed message. Often empty, see KeyID instead.
+func (gs *GpgSignature) Signer() string {
+	return gs.signer
+}
+
+// Date of signing as written in the signed message
+func (gs *GpgSignature) Date() string {
+	return gs.date
+}
+
+// Role of the signature, usually "builder"
+func (gs *GpgSignature) Role() string {
+	return gs.role
+}
+
+// Files returns package members with their signed digests
+func (gs *GpgSignature) Files() []SignedFile {
+	return gs.files
+}
+
+// SignatureVersion returns version of the OpenPGP signature packet
+func (gs *GpgSignature) SignatureVersion() int {
+	return gs.sigVersion
+}
+
+// SignatureType returns OpenPGP signature type, 0x01 for a canonical text document
+func (gs *GpgSignature) SignatureType() int {
+	return gs.sigType
+}
+
+// PublicKeyAlgorithm returns OpenPGP public key algorithm ID, e.g. 1 for RSA, 17 for DSA
+func (gs *GpgSignature) PublicKeyAlgorithm() int {
+	return gs.pubKeyAlgo
+}
+
+// HashAlgorithm returns OpenPGP hash algorithm ID, e.g. 2 for SHA1, 8 for SHA256
+func (gs *GpgSignature) HashAlgorithm() int {
+	return gs.hashAlgo
+}
+
+// KeyID returns the issuer key ID in hexadecimal (16 digits)
+func (gs *GpgSignature) KeyID() string {
+	return gs.keyID
+}
+
+// Fingerprint returns the issuer key fingerprint in hexadecimal, if present in the signature
+func (gs *GpgSignature) Fingerprint() string {
+	return gs.fingerprint
+}
+
+// Created returns the signature creation time
+func (gs *GpgSignature) Created() time.Time {
+	return gs.created
+}
@@ -0,0 +1,180 @@
+package deb
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Synthetic _gpgbuilder member, not written by dpkg-sig itself: the message is laid out as
+// "dpkg-sig --sign builder" does it, with digests of the members of a package built by dpkg-deb,
+// and clearsigned with gpg by a throwaway test key at the time of the Date field.
+const testGpgBuilder = `-----BEGIN PGP SIGNED MESSAGE-----
+Hash: SHA256
+
+Version: 4
+Signer: 
+Date: Tue Nov 14 22:13:20 2023
+Role: builder
+Files: 
+	3cf918272ffa5de195752d73f3da3e5e 7959c969e092f2a5a8604e2287807ac5b1b384ad 4 debian-binary
+	b566afde4bbb6809dd4dd6fd8121f3af 10bb62cfd34bebd6ddbae772fb47c4c987340b54 221 control.tar.gz
+	688e5b9c2fb14c531fbae3dd5b68be3f 25b30cbe74c851c79c6cd505aa19922b0cf02bc9 190 data.tar.gz
+-----BEGIN PGP SIGNATURE-----
+
+iQEzBAEBCAAdFiEEwz1VXT4WQqib/FUqZ7Zoq+h0qJoFAmVT8QAACgkQZ7Zoq+h0
+qJpFeQgAnWXbpTOug3YgXG2+ve8oxTLQiKA/SBYOmedYkGV5FRjLoLO7I6pI/nL6
+wLdfOfpk0b/F91ZCJkcg9lRH7O0OmUAyhN2WzXTX9YGdZIciTuSr+T1kx6kJlb1Q
+AdL4AFxOy1yJ2tkuEZfnN5VIKY2L5jjpL2BXKXYCkDXUO+xmEHbDDjczeZiVwRFK
+r3kX0gA6bibMp5TwNyt5eJLwTFeJauj5RRTf5rU9knQiPrS7TrrkLbKNtoYCNaVH
+C/dN4XhiTsP9JMHI77HYijfFf6UitbcHAO8l577sFVJCF1+8I8/XvMlkfzo6DZCk
+Q6h2pJj/Cjn6eFyofxgNVZ1YpgDibA==
+=3SHH
+-----END PGP SIGNATURE-----
+`
+
+func TestGpgSignatureParse(t *testing.T) {
+	gs := NewGpgSignature()
+	if err := gs.parse(testGpgBuilder); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tt := range []struct {
+		name     string
+		got      interface{}
+		expected interface{}
+	}{
+		{"Hash", gs.Hash(), "SHA256"},
+		{"Version", gs.Version(), "4"},
+		{"Signer", gs.Signer(), ""},
+		{"Date", gs.Date(), "Tue Nov 14 22:13:20 2023"},
+		{"Role", gs.Role(), "builder"},
+		{"SignatureVersion", gs.SignatureVersion(), 4},
+		{"SignatureType", gs.SignatureType(), 1},
+		{"PublicKeyAlgorithm", gs.PublicKeyAlgorithm(), 1},
+		{"HashAlgorithm", gs.HashAlgorithm(), 8},
+		{"KeyID", gs.KeyID(), "67B668ABE874A89A"},
+		{"Fingerprint", gs.Fingerprint(), "C33D555D3E1642A89BFC552A67B668ABE874A89A"},
+		{"Created", gs.Created(), time.Date(2023, time.November, 14, 22, 13, 20, 0, time.UTC)},
+		{"Files", gs.Files(), []SignedFile{
+			{name: "debian-binary", md5: "3cf918272ffa5de195752d73f3da3e5e", sha1: "7959c969e092f2a5a8604e2287807ac5b1b384ad", size: 4},
+			{name: "control.tar.gz", md5: "b566afde4bbb6809dd4dd6fd8121f3af", sha1: "10bb62cfd34bebd6ddbae772fb47c4c987340b54", size: 221},
+			{name: "data.tar.gz", md5: "688e5b9c2fb14c531fbae3dd5b68be3f", sha1: "25b30cbe74c851c79c6cd505aa19922b0cf02bc9", size: 190},
+		}},
+	} {
+		if !reflect.DeepEqual(tt.got, tt.expected) {
+			t.Errorf("%s() = %v, expected %v", tt.name, tt.got, tt.expected)
+		}
+	}
+
+	if date, err := time.Parse(time.ANSIC, gs.Date()); err != nil || !date.Equal(gs.Created()) {
+		t.Errorf("signed Date %q does not match signature creation time %v", gs.Date(), gs.Created())
+	}
+
+	// Same member with CRLF line endings
+	gs = NewGpgSignature()
+	if err := gs.parse(strings.Replace(testGpgBuilder, "\n", "\r\n", -1)); err != nil {
+		t.Fatal(err)
+	}
+	if len(gs.Files()) != 3 || gs.KeyID() != "67B668ABE874A89A" {
+		t.Errorf("unexpected CRLF signature: %v %s", gs.Files(), gs.KeyID())
+	}
+}
+
+func TestGpgSignatureParseErrors(t *testing.T) {
+	for _, data := range []string{
+		"",
+		strings.SplitN(testGpgBuilder, "-----BEGIN PGP SIGNATURE", 2)[0],
+		strings.Replace(testGpgBuilder, " 221 control.tar.gz", " control.tar.gz", 1),
+		strings.Replace(testGpgBuilder, " 221 control.tar.gz", " 22x control.tar.gz", 1),
+		strings.Replace(testGpgBuilder, "Role: builder", "Role builder", 1),
+		strings.Replace(testGpgBuilder, "iQEzBAEBCAAd", "iQEzBAEBCAA!", 1),
+	} {
+		if err := NewGpgSignature().parse(data); err == nil {
+			t.Errorf("parse expected to fail on:\n%s", data)
+		}
+	}
+}
+
+func TestParseSignaturePacket(t *testing.T) {
+	tests := []struct {
+		name        string
+		packet      []byte
+		sigVersion  int
+		sigType     int
+		pubKeyAlgo  int
+		hashAlgo    int
+		keyID       string
+		fingerprint string
+		created     int64
+	}{
+		{
+			// Old format, 1 byte length, v3 signature with DSA and SHA1
+			"v3",
+			[]byte{0x88, 19, 3, 5, 0x00, 0x38, 0x9a, 0x0b, 0x40,
+				0x12, 0x34, 0x56, 0x78, 0x9a, 0xbc, 0xde, 0xf0, 17, 2, 0xab, 0xcd},
+			3, 0, 17, 2, "123456789ABCDEF0", "", 0x389a0b40,
+		},
+		{
+			// New format, 1 byte length, v4 signature with creation time hashed and issuer unhashed
+			"v4 issuer",
+			[]byte{0xc2, 26, 4, 0x01, 1, 10, 0, 6, 5, 2, 0x5e, 0x0b, 0xe1, 0x00,
+				0, 10, 9, 16, 0x68, 0x6c, 0xe9, 0x33, 0x18, 0x3f, 0x52, 0x41, 0xab, 0xcd},
+			4, 1, 1, 10, "686CE933183F5241", "", 0x5e0be100,
+		},
+		{
+			// New format, 2 byte length, v4 signature with critical fingerprint subpacket only
+			"v4 fingerprint",
+			append([]byte{0xc2, 192, 30, 4, 0x00, 22, 8, 0, 23, 22, 0x80 | 33, 4,
+				0xce, 0xca, 0x04, 0x78, 0xc6, 0xe5, 0x57, 0x21, 0x59, 0xc7,
+				0x2c, 0xc9, 0x68, 0x6c, 0xe9, 0x33, 0x18, 0x3f, 0x52, 0x41,
+				0, 0, 0xab, 0xcd}, make([]byte, 189)...),
+			4, 0, 22, 8, "686CE933183F5241", "CECA0478C6E5572159C72CC9686CE933183F5241", 0,
+		},
+	}
+
+	for _, tt := range tests {
+		gs := NewGpgSignature()
+		if err := gs.parseSignaturePacket(tt.packet); err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		created := time.Time{}
+		if tt.created != 0 {
+			created = time.Unix(tt.created, 0).UTC()
+		}
+		if gs.SignatureVersion() != tt.sigVersion || gs.SignatureType() != tt.sigType ||
+			gs.PublicKeyAlgorithm() != tt.pubKeyAlgo || gs.HashAlgorithm() != tt.hashAlgo ||
+			gs.KeyID() != tt.keyID || gs.Fingerprint() != tt.fingerprint || !gs.Created().Equal(created) {
+			t.Errorf("%s: unexpected signature v%d type %d algo %d hash %d key %s fpr %s created %v",
+				tt.name, gs.SignatureVersion(), gs.SignatureType(), gs.PublicKeyAlgorithm(), gs.HashAlgorithm(),
+				gs.KeyID(), gs.Fingerprint(), gs.Created())
+		}
+	}
+}
+
+func TestParseSignaturePacketErrors(t *testing.T) {
+	for _, tt := range []struct {
+		name   string
+		packet []byte
+	}{
+		{"empty", []byte{}},
+		{"not a packet", []byte{0x04, 0x01, 0x00}},
+		{"truncated old format", []byte{0x88, 19, 3, 5, 0x00}},
+		{"truncated new format", []byte{0xc2, 28, 4, 0x01, 1, 10}},
+		{"truncated length", []byte{0x89, 0x01}},
+		{"partial length", []byte{0xc2, 224, 4}},
+		{"public key packet", []byte{0x98, 2, 4, 0}},
+		{"empty signature", []byte{0xc2, 0}},
+		{"truncated v3", []byte{0x88, 3, 3, 5, 0x00}},
+		{"truncated v4", []byte{0xc2, 4, 4, 0x00, 1, 8}},
+		{"truncated subpackets", []byte{0xc2, 8, 4, 0x00, 1, 8, 0, 10, 0, 0}},
+		{"malformed subpacket", []byte{0xc2, 10, 4, 0x00, 1, 8, 0, 2, 5, 2, 0, 0}},
+		{"unsupported version", []byte{0xc2, 2, 2, 0}},
+	} {
+		if err := NewGpgSignature().parseSignaturePacket(tt.packet); err == nil {
+			t.Errorf("%s: parseSignaturePacket expected to fail", tt.name)
+		}
+	}
+}
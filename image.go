@@ -0,0 +1,300 @@
+package deb
+
+import (
+	"archive/tar"
+	"bufio"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"strings"
+)
+
+// Locations of the dpkg status database in an image layer
+const (
+	dpkgStatusPath = "var/lib/dpkg/status"
+	dpkgStatusDir  = "var/lib/dpkg/status.d/" // Distroless images have one file per package
+)
+
+// Prefixes of whiteout files, which delete files of the lower layers
+const (
+	whiteoutPrefix = ".wh."
+	whiteoutOpaque = ".wh..wh..opq" // Deletes all of the directory contents
+)
+
+// LayerInventory are the Debian packages found in a single container image layer
+type LayerInventory struct {
+	index     int
+	name      string
+	installed []*PackageFile
+	archives  []*PackageFile
+	whiteouts []string
+	warnings  []string
+}
+
+// NewLayerInventory constructor
+func NewLayerInventory(index int, name string) *LayerInventory {
+	li := new(LayerInventory)
+	li.index = index
+	li.name = name
+	li.installed = make([]*PackageFile, 0)
+	li.archives = make([]*PackageFile, 0)
+	li.whiteouts = make([]string, 0)
+	li.warnings = make([]string, 0)
+	return li
+}
+
+// Index of the layer in the image, starting from zero for the base layer
+func (li *LayerInventory) Index() int {
+	return li.index
+}
+
+// Name of the layer, e.g. its path in the image archive. Can be empty.
+func (li *LayerInventory) Name() string {
+	return li.name
+}
+
+// Installed returns packages from the dpkg status database files in the layer. They have control data only,
+// see ControlFile().Status() for their state, and their Path() is the database file they were read from.
+//
+// Layers only hold the files they add or change, so this is not the set of packages of the image.
+// A layer with var/lib/dpkg/status replaces the whole database of the lower layers, but a layer with
+// status.d entries (distroless) adds to them, and Whiteouts() deletes them. Merge the layers to get the
+// packages of the image.
+func (li *LayerInventory) Installed() []*PackageFile {
+	return li.installed
+}
+
+// Whiteouts returns the dpkg status database files of the lower layers, that this layer deletes,
+// e.g. "/var/lib/dpkg/status.d/foo". A path ending with a slash means all of the directory contents
+// (opaque whiteout).
+func (li *LayerInventory) Whiteouts() []string {
+	return li.whiteouts
+}
+
+// Archives returns .deb files found in the layer (e.g. apt cache), with their path in the layer
+func (li *LayerInventory) Archives() []*PackageFile {
+	return li.archives
+}
+
+// Warnings returns problems with the files in the layer, that did not stop the scan
+func (li *LayerInventory) Warnings() []string {
+	return li.warnings
+}
+
+// Decompress the layer, if it is gzipped. Plain tar is returned as is.
+// Other compressions (e.g. zstd) are reported as unsupported.
+func layerReader(layer io.Reader) (reader io.Reader, unsupported string, err error) {
+	br := bufio.NewReader(layer)
+	magic, _ := br.Peek(6)
+	switch compression := compressionByMagic(magic); compression {
+	case ".gz":
+		reader, err = gzip.NewReader(br)
+		return reader, "", err
+	case "":
+		return br, "", nil
+	default:
+		return nil, compression, nil
+	}
+}
+
+// Path deleted by a whiteout file, or empty if the file is not a whiteout.
+// Opaque whiteouts delete the directory contents, the path ends with a slash then.
+func whiteoutPath(filename string) string {
+	dir, base := path.Split(filename)
+	switch {
+	case base == whiteoutOpaque:
+		return dir
+	case strings.HasPrefix(base, whiteoutPrefix):
+		return dir + strings.TrimPrefix(base, whiteoutPrefix)
+	}
+	return ""
+}
+
+// Check if the path is the dpkg status database, is within it or contains it
+func isDpkgStatusPath(name string) bool {
+	name = strings.TrimSuffix(name, "/")
+	return name == "" || name == dpkgStatusPath || strings.HasPrefix(name+"/", dpkgStatusDir) ||
+		strings.HasPrefix(dpkgStatusDir, name+"/")
+}
+
+// ScanLayer finds the dpkg status database and .deb files in a container image layer tar stream,
+// which can be gzipped. Layers with other compressions are skipped with a warning. Use ScanImageArchive to scan all layers of an image.
+func ScanLayer(layer io.Reader, opts *PackageOptions) (*LayerInventory, error) {
+	return scanLayer(layer, 0, "", opts)
+}
+
+func scanLayer(layer io.Reader, index int, name string, opts *PackageOptions) (*LayerInventory, error) {
+	li := NewLayerInventory(index, name)
+	reader, unsupported, err := layerReader(layer)
+	if err != nil {
+		return nil, err
+	}
+	if unsupported != "" {
+		li.warnings = append(li.warnings, fmt.Sprintf("layer compression %s is not supported, layer was not scanned", unsupported))
+		return li, nil
+	}
+
+	tr := tar.NewReader(reader)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		filename := strings.TrimPrefix(path.Clean("/"+hdr.Name), "/")
+		if deleted := whiteoutPath(filename); deleted != "" {
+			if isDpkgStatusPath(deleted) {
+				li.whiteouts = append(li.whiteouts, "/"+deleted)
+			}
+			continue
+		}
+		if hdr.Typeflag != tar.TypeReg {
+			continue
+		}
+
+		switch {
+		case filename == dpkgStatusPath || (strings.HasPrefix(filename, dpkgStatusDir) && !strings.HasSuffix(filename, ".md5sums")):
+			data, err := ioutil.ReadAll(tr)
+			if err != nil {
+				return nil, err
+			}
+			for _, p := range ParsePackagesIndex(data) {
+				p.path = "/" + filename
+				li.installed = append(li.installed, p)
+			}
+		case strings.HasSuffix(filename, ".deb"):
+			p, err := readPackage(tr, opts)
+			if err != nil {
+				li.warnings = append(li.warnings, fmt.Sprintf("cannot read package %s: %v", filename, err))
+				continue
+			}
+			p.path, p.fileSize, p.fileTime = "/"+filename, uint64(hdr.Size), hdr.ModTime
+			li.archives = append(li.archives, p)
+		}
+	}
+
+	return li, nil
+}
+
+// ScanLayers scans image layers in their order, from the base layer up
+func ScanLayers(layers []io.Reader, opts *PackageOptions) ([]*LayerInventory, error) {
+	inventories := make([]*LayerInventory, 0, len(layers))
+	for i, layer := range layers {
+		li, err := scanLayer(layer, i, "", opts)
+		if err != nil {
+			return nil, fmt.Errorf("layer %d: %v", i, err)
+		}
+		inventories = append(inventories, li)
+	}
+	return inventories, nil
+}
+
+// Manifest of an image archive, as written by "docker save"
+type imageManifest struct {
+	Layers []string
+}
+
+// Read layer names from manifest.json of the image archive
+func readImageManifest(archive string) ([]string, error) {
+	f, err := os.Open(archive)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	tr := tar.NewReader(f)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil, fmt.Errorf("no manifest.json in image archive %s", archive)
+		}
+		if err != nil {
+			return nil, err
+		}
+		if path.Clean(hdr.Name) != "manifest.json" {
+			continue
+		}
+
+		var manifests []imageManifest
+		if err := json.NewDecoder(tr).Decode(&manifests); err != nil {
+			return nil, err
+		}
+		if len(manifests) == 0 {
+			return nil, fmt.Errorf("empty manifest.json in image archive %s", archive)
+		}
+		return manifests[0].Layers, nil // Only the first image is scanned
+	}
+}
+
+// ScanImageArchive scans all layers of an image archive, as written by "docker save"
+// (both legacy and OCI layouts). Inventories are in the order of layers, from the base layer up.
+func ScanImageArchive(archive string, opts *PackageOptions) ([]*LayerInventory, error) {
+	layers, err := readImageManifest(archive)
+	if err != nil {
+		return nil, err
+	}
+	indexes := make(map[string]int)
+	for i, layer := range layers {
+		indexes[path.Clean(layer)] = i
+	}
+
+	f, err := os.Open(archive)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	// The same blob can be used by multiple layers, e.g. empty ones.
+	// Legacy layout also links duplicate layers to each other.
+	scanned := make(map[string]*LayerInventory)
+	links := make(map[string]string)
+	tr := tar.NewReader(f)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		name := path.Clean(hdr.Name)
+		i, ok := indexes[name]
+		switch {
+		case !ok:
+			continue
+		case hdr.Typeflag == tar.TypeReg:
+			if scanned[name], err = scanLayer(tr, i, name, opts); err != nil {
+				return nil, fmt.Errorf("layer %s: %v", name, err)
+			}
+		case hdr.Typeflag == tar.TypeSymlink:
+			links[name] = path.Join(path.Dir(name), hdr.Linkname)
+		}
+	}
+
+	inventories := make([]*LayerInventory, 0, len(layers))
+	for i, layer := range layers {
+		name := path.Clean(layer)
+		blob := name
+		if target, ok := links[name]; ok {
+			blob = target
+		}
+		li, ok := scanned[blob]
+		if !ok {
+			return nil, fmt.Errorf("layer %s is missing in image archive %s", layer, archive)
+		}
+		if li.index != i || li.name != name {
+			copied := *li
+			copied.index, copied.name = i, name
+			li = &copied
+		}
+		inventories = append(inventories, li)
+	}
+
+	return inventories, nil
+}
@@ -0,0 +1,208 @@
+package deb
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// Names of the packages with their paths
+func testPackageNames(packages []*PackageFile) []string {
+	names := make([]string, 0)
+	for _, p := range packages {
+		names = append(names, p.ControlFile().Package()+" "+p.Path())
+	}
+	return names
+}
+
+// Layer with the dpkg status database, distroless status.d entries, whiteouts and cached packages
+func testLayer() []byte {
+	deb := testDeb(testControl(""), []testEntry{{name: "./"}, {name: "./usr/"}, {name: "./usr/foo", body: "foo\n"}})
+	return testTar(
+		testEntry{name: "var/"}, testEntry{name: "var/lib/"}, testEntry{name: "var/lib/dpkg/"},
+		testEntry{name: "var/lib/dpkg/status", body: "Package: base-files\nStatus: install ok installed\nVersion: 12.4\n\nPackage: libc6\nStatus: install ok installed\nVersion: 2.36-9\nDepends: libgcc-s1\n"},
+		testEntry{name: "./var/lib/dpkg/status.d/tzdata", body: "Package: tzdata\nVersion: 2024a-0+deb12u1\n"},
+		testEntry{name: "var/lib/dpkg/status.d/tzdata.md5sums", body: "d3b07384d113edec49eaa6238ad5ff00  usr/share/zoneinfo/UTC\n"},
+		testEntry{name: "var/lib/dpkg/status.d/.wh.openssl"},
+		testEntry{name: "var/lib/dpkg/info/.wh.openssl.list"},
+		testEntry{name: "var/lib/dpkg/status.d/libssl", link: "tzdata"},
+		testEntry{name: "var/cache/apt/archives/foo_1.0-1_amd64.deb", body: string(deb)},
+		testEntry{name: "var/cache/apt/archives/broken_1.0-1_amd64.deb", body: "!<arch>\nbroken"},
+		testEntry{name: "etc/foo.conf", body: "Package: not-a-database\n"},
+	)
+}
+
+func TestScanLayer(t *testing.T) {
+	layer := testLayer()
+	for _, tt := range []struct {
+		name string
+		data []byte
+	}{
+		{"plain", layer},
+		{"gzipped", testGzip(layer)},
+	} {
+		li, err := ScanLayer(bytes.NewReader(tt.data), DefaultPackageOptions)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if names := testPackageNames(li.Installed()); !reflect.DeepEqual(names, []string{
+			"base-files /var/lib/dpkg/status", "libc6 /var/lib/dpkg/status", "tzdata /var/lib/dpkg/status.d/tzdata",
+		}) {
+			t.Errorf("%s: unexpected installed packages %q", tt.name, names)
+		}
+		if li.Installed()[1].ControlFile().Status() != "install ok installed" || li.Installed()[1].ControlFile().Depends()[0] != "libgcc-s1" {
+			t.Errorf("%s: unexpected control data of libc6", tt.name)
+		}
+		if !reflect.DeepEqual(li.Whiteouts(), []string{"/var/lib/dpkg/status.d/openssl"}) {
+			t.Errorf("%s: unexpected whiteouts %q", tt.name, li.Whiteouts())
+		}
+		if names := testPackageNames(li.Archives()); !reflect.DeepEqual(names, []string{"foo /var/cache/apt/archives/foo_1.0-1_amd64.deb"}) {
+			t.Errorf("%s: unexpected archives %q", tt.name, names)
+		}
+		if len(li.Archives()) == 1 && (len(li.Archives()[0].Files()) != 3 || li.Archives()[0].FileSize() == 0) {
+			t.Errorf("%s: archive was not read completely", tt.name)
+		}
+		if len(li.Warnings()) != 1 {
+			t.Errorf("%s: expected a warning on the broken package, got %q", tt.name, li.Warnings())
+		}
+	}
+}
+
+func TestScanLayerWhiteouts(t *testing.T) {
+	for _, tt := range []struct {
+		name      string
+		whiteouts []string
+	}{
+		{"var/lib/dpkg/status.d/.wh.foo", []string{"/var/lib/dpkg/status.d/foo"}},
+		{"var/lib/dpkg/status.d/.wh..wh..opq", []string{"/var/lib/dpkg/status.d/"}},
+		{"var/lib/dpkg/.wh.status", []string{"/var/lib/dpkg/status"}},
+		{"var/lib/dpkg/.wh.status.d", []string{"/var/lib/dpkg/status.d"}},
+		{"var/lib/.wh..wh..opq", []string{"/var/lib/"}},
+		{"var/lib/dpkg/.wh.status-old", []string{}},
+		{"var/lib/dpkg/info/.wh.foo.list", []string{}},
+		{"etc/.wh.foo", []string{}},
+	} {
+		li, err := ScanLayer(bytes.NewReader(testTar(testEntry{name: tt.name})), DefaultPackageOptions)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(li.Whiteouts(), tt.whiteouts) {
+			t.Errorf("%s: unexpected whiteouts %q, expected %q", tt.name, li.Whiteouts(), tt.whiteouts)
+		}
+	}
+}
+
+func TestScanLayers(t *testing.T) {
+	zstd := []byte{0x28, 0xb5, 0x2f, 0xfd, 0x04, 0x00, 0x01, 0x00, 0x00}
+	inventories, err := ScanLayers([]io.Reader{bytes.NewReader(testLayer()), bytes.NewReader(zstd), bytes.NewReader(testTar())}, DefaultPackageOptions)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(inventories) != 3 {
+		t.Fatalf("expected 3 inventories, got %d", len(inventories))
+	}
+	for i, li := range inventories {
+		if li.Index() != i {
+			t.Errorf("inventory %d has index %d", i, li.Index())
+		}
+	}
+	if len(inventories[0].Installed()) != 3 || len(inventories[1].Warnings()) != 1 || len(inventories[2].Installed()) != 0 {
+		t.Errorf("unexpected inventories: %d installed, %q warnings, %d installed",
+			len(inventories[0].Installed()), inventories[1].Warnings(), len(inventories[2].Installed()))
+	}
+
+	if _, err := ScanLayers([]io.Reader{bytes.NewReader([]byte("not a tar archive, but long enough to be read as a header"))}, DefaultPackageOptions); err == nil {
+		t.Error("ScanLayers of garbage expected to fail")
+	}
+}
+
+func TestScanImageArchive(t *testing.T) {
+	base := testLayer()
+	top := testTar(testEntry{name: "var/lib/dpkg/status.d/extra", body: "Package: extra\nVersion: 1\n"})
+	empty := testTar()
+
+	for _, tt := range []struct {
+		name    string
+		archive []byte
+		layers  []string // Expected layer names
+	}{
+		{
+			// Legacy "docker save": duplicate layers are symlinked to the first one
+			"legacy",
+			testTar(
+				testEntry{name: "manifest.json", body: `[{"Config":"abc.json","RepoTags":["foo:latest"],"Layers":["aaa/layer.tar","bbb/layer.tar","ccc/layer.tar","ddd/layer.tar"]}]`},
+				testEntry{name: "aaa/"}, testEntry{name: "aaa/layer.tar", body: string(base)},
+				testEntry{name: "bbb/"}, testEntry{name: "bbb/layer.tar", body: string(empty)},
+				testEntry{name: "ccc/"}, testEntry{name: "ccc/layer.tar", link: "../bbb/layer.tar"},
+				testEntry{name: "ddd/"}, testEntry{name: "ddd/layer.tar", body: string(testGzip(top))},
+			),
+			[]string{"aaa/layer.tar", "bbb/layer.tar", "ccc/layer.tar", "ddd/layer.tar"},
+		},
+		{
+			// OCI layout: the same blob is listed for multiple layers
+			"oci",
+			testTar(
+				testEntry{name: "oci-layout", body: `{"imageLayoutVersion":"1.0.0"}`},
+				testEntry{name: "blobs/"}, testEntry{name: "blobs/sha256/"},
+				testEntry{name: "blobs/sha256/01", body: string(base)},
+				testEntry{name: "blobs/sha256/02", body: string(empty)},
+				testEntry{name: "blobs/sha256/03", body: string(testGzip(top))},
+				testEntry{name: "manifest.json", body: `[{"Config":"blobs/sha256/ff","Layers":["blobs/sha256/01","blobs/sha256/02","blobs/sha256/02","blobs/sha256/03"]}]`},
+			),
+			[]string{"blobs/sha256/01", "blobs/sha256/02", "blobs/sha256/02", "blobs/sha256/03"},
+		},
+	} {
+		dir, err := ioutil.TempDir("", "go-deb")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer os.RemoveAll(dir)
+		archive := filepath.Join(dir, "image.tar")
+		if err := ioutil.WriteFile(archive, tt.archive, 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		inventories, err := ScanImageArchive(archive, DefaultPackageOptions)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if len(inventories) != len(tt.layers) {
+			t.Fatalf("%s: expected %d inventories, got %d", tt.name, len(tt.layers), len(inventories))
+		}
+		for i, li := range inventories {
+			if li.Index() != i || li.Name() != tt.layers[i] {
+				t.Errorf("%s: inventory %d is %d %s, expected %s", tt.name, i, li.Index(), li.Name(), tt.layers[i])
+			}
+		}
+		if len(inventories[0].Installed()) != 3 || len(inventories[1].Installed()) != 0 || len(inventories[2].Installed()) != 0 {
+			t.Errorf("%s: unexpected packages in the lower layers", tt.name)
+		}
+		if names := testPackageNames(inventories[3].Installed()); !reflect.DeepEqual(names, []string{"extra /var/lib/dpkg/status.d/extra"}) {
+			t.Errorf("%s: unexpected packages of the top layer %q", tt.name, names)
+		}
+	}
+
+	// Missing layer and missing manifest
+	dir, err := ioutil.TempDir("", "go-deb")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	for name, archive := range map[string][]byte{
+		"missing-layer.tar":    testTar(testEntry{name: "manifest.json", body: `[{"Layers":["aaa/layer.tar"]}]`}),
+		"missing-manifest.tar": testTar(testEntry{name: "aaa/layer.tar", body: string(empty)}),
+		"empty-manifest.tar":   testTar(testEntry{name: "manifest.json", body: `[]`}),
+	} {
+		filename := filepath.Join(dir, name)
+		if err := ioutil.WriteFile(filename, archive, 0644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := ScanImageArchive(filename, DefaultPackageOptions); err == nil {
+			t.Errorf("ScanImageArchive of %s expected to fail", name)
+		}
+	}
+}
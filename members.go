@@ -0,0 +1,85 @@
+package deb
+
+import (
+	"bytes"
+	"strings"
+)
+
+// Strictness flags. By default packages that violate the format specification, but are still
+// accepted by dpkg, are read and the violations are recorded as package warnings.
+// Each flag turns the respective violation into an error instead.
+const (
+	// Reject packages without "debian-binary" member
+	STRICT_DEBIAN_BINARY = 1 << iota
+	// Reject members in unusual order, e.g. control archive after the data archive
+	STRICT_MEMBER_ORDER
+	// Reject members with odd names, e.g. "Control.tar.gz", "data.tar.gz.1" or unknown members
+	STRICT_MEMBER_NAMES
+
+	STRICT_ALL = STRICT_DEBIAN_BINARY | STRICT_MEMBER_ORDER | STRICT_MEMBER_NAMES
+)
+
+// Kinds of the ar members of a package
+const (
+	memberUnknown = iota
+	memberDebianBinary
+	memberControl
+	memberData
+	memberGpgBuilder
+	memberIgnored // Any other member starting with underscore, as dpkg does
+)
+
+// Compression suffixes of the control and data archives. Empty is plain tar.
+var memberCompressions = []string{"", ".gz", ".xz", ".bz2", ".lzma", ".zst"}
+
+// Classify ar member by its name. Odd names are those, which are not
+// as per specification, but could still be recognised.
+func classifyMember(name string) (kind int, odd bool) {
+	switch {
+	case name == "debian-binary":
+		return memberDebianBinary, false
+	case name == "_gpgbuilder":
+		return memberGpgBuilder, false
+	case strings.HasPrefix(name, "_"):
+		return memberIgnored, false
+	case strings.HasPrefix(name, "control.tar") && in(strings.TrimPrefix(name, "control.tar"), memberCompressions):
+		return memberControl, false
+	case strings.HasPrefix(name, "data.tar") && in(strings.TrimPrefix(name, "data.tar"), memberCompressions):
+		return memberData, false
+	}
+
+	name = strings.ToLower(strings.TrimSpace(name))
+	switch {
+	case name == "debian-binary":
+		return memberDebianBinary, true
+	case strings.HasPrefix(name, "control."):
+		return memberControl, true
+	case strings.HasPrefix(name, "data."):
+		return memberData, true
+	}
+
+	return memberUnknown, true
+}
+
+// Guess compression suffix by the magic bytes of the data.
+// Returns an empty string for plain tar or unknown data.
+func compressionByMagic(data []byte) string {
+	switch {
+	case bytes.HasPrefix(data, []byte{0x1f, 0x8b}):
+		return ".gz"
+	case bytes.HasPrefix(data, []byte{0xfd, '7', 'z', 'X', 'Z', 0x00}):
+		return ".xz"
+	case bytes.HasPrefix(data, []byte("BZh")):
+		return ".bz2"
+	case bytes.HasPrefix(data, []byte{0x5d, 0x00, 0x00}):
+		return ".lzma"
+	case bytes.HasPrefix(data, []byte{0x28, 0xb5, 0x2f, 0xfd}):
+		return ".zst"
+	}
+	return ""
+}
+
+// Check if the data is a (ustar or GNU) tar archive. Empty data is an empty archive.
+func isTar(data []byte) bool {
+	return len(data) == 0 || len(data) >= 262 && string(data[257:262]) == "ustar"
+}
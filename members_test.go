@@ -0,0 +1,103 @@
+package deb
+
+import "testing"
+
+func TestClassifyMember(t *testing.T) {
+	for _, tt := range []struct {
+		name string
+		kind int
+		odd  bool
+	}{
+		{"debian-binary", memberDebianBinary, false},
+		{"control.tar", memberControl, false},
+		{"control.tar.gz", memberControl, false},
+		{"control.tar.zst", memberControl, false},
+		{"data.tar.xz", memberData, false},
+		{"data.tar.bz2", memberData, false},
+		{"data.tar.lzma", memberData, false},
+		{"_gpgbuilder", memberGpgBuilder, false},
+		{"_gpgorigin", memberIgnored, false},
+		{"Debian-Binary ", memberDebianBinary, true},
+		{"control.tgz", memberControl, true},
+		{"Control.tar.gz", memberControl, true},
+		{"data.tar.gz.1", memberData, true},
+		{"data.gz", memberData, true},
+		{"foo", memberUnknown, true},
+	} {
+		if kind, odd := classifyMember(tt.name); kind != tt.kind || odd != tt.odd {
+			t.Errorf("classifyMember(%q) = %d, %v, expected %d, %v", tt.name, kind, odd, tt.kind, tt.odd)
+		}
+	}
+}
+
+func TestMemberViolations(t *testing.T) {
+	debianBinary := testMember{"debian-binary", []byte("2.0\n")}
+	control := testGzip(testTar(testControl("")...))
+	data := testGzip(testTar(testEntry{name: "./"}, testEntry{name: "./usr/"}, testEntry{name: "./usr/foo", body: "foo\n"}))
+	zstd := []byte{0x28, 0xb5, 0x2f, 0xfd, 0x04, 0x00, 0x01, 0x00, 0x00}
+
+	tests := []struct {
+		name     string
+		members  []testMember
+		flag     int  // STRICT_* flag rejecting the package, zero if none does
+		warnings int  // Warnings recorded by default
+		complete bool // Package is expected to have its control data and files
+	}{
+		{"standard", []testMember{debianBinary, {"control.tar.gz", control}, {"data.tar.gz", data}}, 0, 0, true},
+		{"plain tar", []testMember{debianBinary, {"control.tar", testTar(testControl("")...)}, {"data.tar", testTar(testEntry{name: "./usr/foo", body: "foo\n"})}}, 0, 0, true},
+		{"ignored member", []testMember{debianBinary, {"control.tar.gz", control}, {"data.tar.gz", data}, {"_extra", []byte("foo")}}, 0, 0, true},
+		{"missing debian-binary", []testMember{{"control.tar.gz", control}, {"data.tar.gz", data}}, STRICT_DEBIAN_BINARY, 1, true},
+		{"data before control", []testMember{debianBinary, {"data.tar.gz", data}, {"control.tar.gz", control}}, STRICT_MEMBER_ORDER, 2, true},
+		{"debian-binary last", []testMember{{"control.tar.gz", control}, {"data.tar.gz", data}, debianBinary}, STRICT_MEMBER_ORDER, 1, true},
+		{"tgz", []testMember{debianBinary, {"control.tgz", control}, {"data.tgz", data}}, STRICT_MEMBER_NAMES, 2, true},
+		{"no tar suffix", []testMember{debianBinary, {"control.gz", control}, {"data.gz", data}}, STRICT_MEMBER_NAMES, 2, true},
+		{"capitalised", []testMember{debianBinary, {"Control.tar.gz", control}, {"data.tar.gz", data}}, STRICT_MEMBER_NAMES, 1, true},
+		{"unknown member", []testMember{debianBinary, {"control.tar.gz", control}, {"data.tar.gz", data}, {"foo", []byte("foo")}}, STRICT_MEMBER_NAMES, 1, true},
+		{"zstd", []testMember{debianBinary, {"control.tar.zst", zstd}, {"data.tar.zst", zstd}}, 0, 2, false},
+		{"not a tar", []testMember{debianBinary, {"control.tar.gz", control}, {"data.tar", []byte("not a tar archive")}}, 0, 1, false},
+	}
+
+	for _, tt := range tests {
+		data := testAr(tt.members...)
+
+		p, err := testRead(data, &PackageOptions{Strict: 0})
+		if err != nil {
+			t.Errorf("%s: unexpected error by default: %v", tt.name, err)
+			continue
+		}
+		if len(p.Warnings()) != tt.warnings {
+			t.Errorf("%s: expected %d warnings, got %q", tt.name, tt.warnings, p.Warnings())
+		}
+		if complete := p.ControlFile().Package() == "foo" && len(p.Files()) > 0; complete != tt.complete {
+			t.Errorf("%s: package read completely: %v, expected %v", tt.name, complete, tt.complete)
+		}
+
+		if _, err := testRead(data, &PackageOptions{Strict: STRICT_ALL &^ tt.flag}); err != nil {
+			t.Errorf("%s: unexpected error with other flags: %v", tt.name, err)
+		}
+		if tt.flag != 0 {
+			if _, err := testRead(data, &PackageOptions{Strict: tt.flag}); err == nil {
+				t.Errorf("%s: expected error with flag %d", tt.name, tt.flag)
+			}
+		}
+	}
+}
+
+func TestCompressionByMagic(t *testing.T) {
+	for _, tt := range []struct {
+		data        []byte
+		compression string
+	}{
+		{testGzip([]byte("foo")), ".gz"},
+		{[]byte{0xfd, '7', 'z', 'X', 'Z', 0x00, 0x00}, ".xz"},
+		{[]byte("BZh91AY&SY"), ".bz2"},
+		{[]byte{0x5d, 0x00, 0x00, 0x80, 0x00}, ".lzma"},
+		{[]byte{0x28, 0xb5, 0x2f, 0xfd}, ".zst"},
+		{testTar(testEntry{name: "./"}), ""},
+		{[]byte{}, ""},
+	} {
+		if compression := compressionByMagic(tt.data); compression != tt.compression {
+			t.Errorf("compressionByMagic() = %q, expected %q", compression, tt.compression)
+		}
+	}
+}
@@ -0,0 +1,79 @@
+package deb
+
+import (
+	"bytes"
+	"crypto/md5"
+	"fmt"
+	"path"
+	"strings"
+)
+
+// Fields of the control file that are replaced by PackagesEntry
+var packagesEntryFields = []string{"filename", "size", "md5sum", "sha1", "sha256", "description-md5"}
+
+// PackagesEntry returns the stanza of this package for the repository Packages index:
+// all control fields, followed by Filename, Size, MD5sum, SHA1, SHA256 and Description-md5.
+//
+// The poolPath is the directory of the package in the repository, e.g. "pool/main/f/foo".
+// If it ends with ".deb", it is taken as the complete Filename instead.
+// The package has to be opened with OpenPackageFile from a local path, so its checksums
+// can be calculated.
+func (c *PackageFile) PackagesEntry(poolPath string) (string, error) {
+	if len(c.control.raw) == 0 {
+		return "", fmt.Errorf("package has no control file")
+	}
+	if c.checksum == nil || isPackageURL(c.path) {
+		return "", fmt.Errorf("package was not opened from a local file, checksums cannot be calculated")
+	}
+
+	md5sum, sha1sum, sha256sum, err := c.checksum.computeAll()
+	if err != nil {
+		return "", err
+	}
+
+	filename := poolPath
+	if !strings.HasSuffix(poolPath, ".deb") {
+		filename = path.Join(poolPath, path.Base(c.path))
+	}
+
+	var entry strings.Builder
+	var description string
+	hasDescription := false
+	fields, _ := splitControlFields(c.control.raw)
+	for _, field := range fields {
+		if in(strings.ToLower(field.name), packagesEntryFields) {
+			continue
+		}
+		if strings.ToLower(field.name) == "description" {
+			description, hasDescription = field.value, true
+		}
+		fmt.Fprintf(&entry, "%s: %s\n", field.name, field.value)
+	}
+
+	fmt.Fprintf(&entry, "Filename: %s\n", filename)
+	fmt.Fprintf(&entry, "Size: %d\n", c.fileSize)
+	fmt.Fprintf(&entry, "MD5sum: %s\n", md5sum)
+	fmt.Fprintf(&entry, "SHA1: %s\n", sha1sum)
+	fmt.Fprintf(&entry, "SHA256: %s\n", sha256sum)
+	if hasDescription {
+		// Same as apt does: full description as in the control file, with a trailing newline
+		fmt.Fprintf(&entry, "Description-md5: %x\n", md5.Sum([]byte(description+"\n")))
+	}
+
+	return entry.String(), nil
+}
+
+// ParsePackagesIndex reads all stanzas of a repository Packages index.
+// Packages have their control data only: no files, scripts or checksums.
+func ParsePackagesIndex(data []byte) []*PackageFile {
+	packages := make([]*PackageFile, 0)
+	for _, stanza := range bytes.Split(bytes.ReplaceAll(data, []byte("\r\n"), []byte("\n")), []byte("\n\n")) {
+		if len(bytes.TrimSpace(stanza)) == 0 {
+			continue
+		}
+		p := NewPackageFile()
+		p.parseControlFile(stanza)
+		packages = append(packages, p)
+	}
+	return packages
+}
@@ -0,0 +1,103 @@
+package deb
+
+import (
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const testHelloControl = `Package: hello
+Version: 2.10-3
+Architecture: amd64
+Maintainer: Santiago Vila <sanvila@debian.org>
+Installed-Size: 280
+Depends: libc6 (>= 2.34)
+Section: devel
+Priority: optional
+Homepage: https://www.gnu.org/software/hello/
+Description: example package based on GNU hello
+ The GNU hello program produces a familiar, friendly greeting.
+ .
+ It allows non-programmers to use a classic computer science tool.
+`
+
+func TestPackagesEntry(t *testing.T) {
+	data := testAr(
+		testMember{"debian-binary", []byte("2.0\n")},
+		testMember{"control.tar.gz", testGzip(testTar(testEntry{name: "./"}, testEntry{name: "./control", body: testHelloControl}))},
+		testMember{"data.tar.gz", testGzip(testTar(testEntry{name: "./"}))},
+	)
+	dir, err := ioutil.TempDir("", "go-deb")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	filename := filepath.Join(dir, "hello_2.10-3_amd64.deb")
+	if err := ioutil.WriteFile(filename, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	p, err := OpenPackageFile(filename, DefaultPackageOptions)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := func(filename string) string {
+		return testHelloControl + "Filename: " + filename + "\n" + fmt.Sprintf("Size: %d\n", len(data)) +
+			fmt.Sprintf("MD5sum: %x\nSHA1: %x\nSHA256: %x\n", md5.Sum(data), sha1.Sum(data), sha256.Sum256(data)) +
+			"Description-md5: b45ca572b4057ebb3d95f38d1f63316c\n"
+	}
+
+	for _, tt := range []struct {
+		poolPath string
+		filename string
+	}{
+		{"pool/main/h/hello", "pool/main/h/hello/hello_2.10-3_amd64.deb"},
+		{"pool/main/h/hello/hello.deb", "pool/main/h/hello/hello.deb"},
+	} {
+		entry, err := p.PackagesEntry(tt.poolPath)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if entry != expected(tt.filename) {
+			t.Errorf("PackagesEntry(%q) =\n%s\nexpected\n%s", tt.poolPath, entry, expected(tt.filename))
+		}
+	}
+
+	// Fields of an existing entry are replaced, not duplicated
+	p.parseControlFile([]byte(testHelloControl + "Filename: old.deb\nSize: 1\nMD5sum: 0\n"))
+	if entry, err := p.PackagesEntry("pool"); err != nil || entry != expected("pool/hello_2.10-3_amd64.deb") {
+		t.Errorf("unexpected entry of a package with index fields:\n%s %v", entry, err)
+	}
+
+	// Packages read from a stream or over HTTP have no local file
+	p, err = testRead(data, DefaultPackageOptions)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := p.PackagesEntry("pool"); err == nil {
+		t.Error("PackagesEntry of a package without a local file expected to fail")
+	}
+	p.setPath("http://deb.debian.org/debian/pool/main/h/hello/hello_2.10-3_amd64.deb")
+	if _, err := p.PackagesEntry("pool"); err == nil {
+		t.Error("PackagesEntry of a package opened over HTTP expected to fail")
+	}
+}
+
+func TestParsePackagesIndex(t *testing.T) {
+	index := testHelloControl + "Filename: pool/main/h/hello/hello_2.10-3_amd64.deb\n\nPackage: foo\r\nVersion: 1.0\r\nDepends: bar,\r\n baz\r\n\r\n\n"
+	packages := ParsePackagesIndex([]byte(index))
+	if len(packages) != 2 {
+		t.Fatalf("expected 2 packages, got %d", len(packages))
+	}
+	if cf := packages[0].ControlFile(); cf.Package() != "hello" || cf.InstalledSize() != 280 || cf.Summary() != "example package based on GNU hello." {
+		t.Errorf("unexpected first package: %s %d %q", cf.Package(), cf.InstalledSize(), cf.Summary())
+	}
+	if depends, err := packages[1].ControlFile().Relations("depends"); err != nil || len(depends) != 2 {
+		t.Errorf("unexpected second package relations: %v %v", depends, err)
+	}
+}
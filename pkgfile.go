@@ -45,6 +45,14 @@ type PackageOptions struct {
 	// Usually it is a very good idea to do so, but not needed if the package
 	// information is not intended to be used for system verification.
 	RecalculateChecksums bool
+
+	// Stream maintainer scripts to this sink instead of keeping them in memory.
+	// Script meta-data is available regardless.
+	ScriptSink ScriptSink
+
+	// Reject packages, which violate the format, but are still installable by dpkg.
+	// This is a combination of STRICT_* flags. Accepted violations are recorded as package warnings.
+	Strict int
 }
 
 var DefaultPackageOptions = &PackageOptions{
@@ -57,7 +65,7 @@ var DefaultPackageOptions = &PackageOptions{
 func OpenPackageFile(uri string, opts *PackageOptions) (*PackageFile, error) {
 	var pf *PackageFile
 	var err error
-	if strings.Contains(uri, "://") && strings.HasPrefix(strings.ToLower(uri), "http") {
+	if isPackageURL(uri) {
 		pf, err = openPackageURL(uri, opts)
 	} else {
 		pf, err = openPackagePath(uri, opts)
@@ -66,6 +74,11 @@ func OpenPackageFile(uri string, opts *PackageOptions) (*PackageFile, error) {
 	return pf, err
 }
 
+// Check if the package is to be opened over HTTP
+func isPackageURL(uri string) bool {
+	return strings.Contains(uri, "://") && strings.HasPrefix(strings.ToLower(uri), "http")
+}
+
 func openPackagePath(path string, opts *PackageOptions) (*PackageFile, error) {
 	f, err := os.Open(path)
 	if err != nil {
@@ -78,7 +91,7 @@ func openPackagePath(path string, opts *PackageOptions) (*PackageFile, error) {
 		return nil, err
 	}
 
-	p, err := NewPackageFileReader(f).SetMetaonly(opts.MetaOnly).SetHash(opts.Hash).Read()
+	p, err := readPackage(f, opts)
 	if err != nil {
 		return nil, err
 	}
@@ -95,7 +108,7 @@ func openPackageURL(path string, opts *PackageOptions) (*PackageFile, error) {
 	}
 	defer resp.Body.Close()
 
-	p, err := NewPackageFileReader(resp.Body).SetMetaonly(opts.MetaOnly).SetHash(opts.Hash).Read()
+	p, err := readPackage(resp.Body, opts)
 	if err != nil {
 		return nil, err
 	}
@@ -107,6 +120,16 @@ func openPackageURL(path string, opts *PackageOptions) (*PackageFile, error) {
 	return p, nil
 }
 
+// Read package with the options, turning panics of the reader (e.g. on corrupted archives) into errors
+func readPackage(reader io.Reader, opts *PackageOptions) (p *PackageFile, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			p, err = nil, fmt.Errorf("%v", r)
+		}
+	}()
+	return newOptionsReader(reader, opts).Read()
+}
+
 // PackageFileReader object
 type PackageFileReader struct {
 	reader   io.Reader
@@ -114,6 +137,12 @@ type PackageFileReader struct {
 	arcnt    *ar.Reader
 	metaonly bool
 	hash     int
+	member   *MemberStats // Statistics of the member being processed
+	body     io.Reader    // Data of the member being processed
+
+	scriptSink ScriptSink
+	strict     int
+	seen       map[int]bool // Kinds of members already read
 }
 
 // PackageFileReader constructor
@@ -123,10 +152,16 @@ func NewPackageFileReader(reader io.Reader) *PackageFileReader {
 	pfr.pkg = NewPackageFile()
 	pfr.arcnt = ar.NewReader(pfr.reader)
 	pfr.metaonly = true
+	pfr.seen = make(map[int]bool)
 
 	return pfr
 }
 
+// PackageFileReader configured with the package options
+func newOptionsReader(reader io.Reader, opts *PackageOptions) *PackageFileReader {
+	return NewPackageFileReader(reader).SetMetaonly(opts.MetaOnly).SetHash(opts.Hash).SetScriptSink(opts.ScriptSink).SetStrict(opts.Strict)
+}
+
 func (pfr *PackageFileReader) SetMetaonly(metaonly bool) *PackageFileReader {
 	pfr.metaonly = metaonly
 	return pfr
@@ -138,6 +173,19 @@ func (pfr *PackageFileReader) SetHash(hash int) *PackageFileReader {
 	return pfr
 }
 
+// SetScriptSink to stream maintainer scripts to, instead of keeping them in memory
+func (pfr *PackageFileReader) SetScriptSink(sink ScriptSink) *PackageFileReader {
+	pfr.scriptSink = sink
+	return pfr
+}
+
+// SetStrict to reject packages that violate the format, see STRICT_* flags.
+// By default violations are only recorded as package warnings.
+func (pfr *PackageFileReader) SetStrict(strict int) *PackageFileReader {
+	pfr.strict = strict
+	return pfr
+}
+
 // Error checker
 func (pfr PackageFileReader) checkErr(err error) bool {
 	if err != nil {
@@ -146,24 +194,48 @@ func (pfr PackageFileReader) checkErr(err error) bool {
 	return err == nil
 }
 
-// Decompress Tar data from gz or xz
+// Decompress Tar data from gz or xz. Members with unsupported compression (zstd) or unknown data
+// are skipped with a package warning, nil is returned then.
 func (pfr *PackageFileReader) decompressTar(header ar.Header) *tar.Reader {
 	gzbuf := &bytes.Buffer{}
 	trbuf := &bytes.Buffer{}
 
-	_, cperr := io.Copy(gzbuf, pfr.arcnt)
+	_, cperr := io.Copy(gzbuf, pfr.body)
 	pfr.checkErr(cperr)
+	pfr.member.observeBuffer(gzbuf.Len())
 
-	if strings.HasSuffix(header.Name, ".gz") {
+	// Odd member names (e.g. "control.tgz") might not tell the compression, so look at the data then
+	compression := ""
+	i := strings.LastIndex(header.Name, ".tar")
+	if i > -1 {
+		compression = header.Name[i+len(".tar"):]
+	}
+	if i < 0 || !in(compression, memberCompressions) {
+		compression = compressionByMagic(gzbuf.Bytes())
+	}
+
+	switch compression {
+	case ".gz":
 		pfr.checkErr(pfr.pkg.unGzip(trbuf, gzbuf.Bytes()))
-	} else if strings.HasSuffix(header.Name, ".xz") {
+	case ".xz":
 		pfr.checkErr(pfr.pkg.unXz(trbuf, gzbuf.Bytes()))
-	} else if strings.HasSuffix(header.Name, ".bz2") {
+	case ".bz2":
 		pfr.checkErr(pfr.pkg.unBzip(trbuf, gzbuf.Bytes()))
-	} else if strings.HasSuffix(header.Name, ".lzma") {
+	case ".lzma":
 		pfr.checkErr(pfr.pkg.unLzma(trbuf, gzbuf.Bytes()))
+	case ".zst":
+		pfr.pkg.warnings = append(pfr.pkg.warnings, fmt.Sprintf("member '%s' is compressed with zstd, which is not supported, its contents were skipped", header.Name))
+		return nil
+	default:
+		if !isTar(gzbuf.Bytes()) {
+			pfr.pkg.warnings = append(pfr.pkg.warnings, fmt.Sprintf("member '%s' is not a tar archive or has unknown compression, its contents were skipped", header.Name))
+			return nil
+		}
+		trbuf.Write(gzbuf.Bytes()) // Plain tar
 	}
 
+	pfr.member.bytesDecompressed = int64(trbuf.Len())
+	pfr.member.observeBuffer(gzbuf.Len() + trbuf.Len())
 	gzbuf.Reset()
 
 	return tar.NewReader(trbuf)
@@ -173,9 +245,14 @@ func (pfr *PackageFileReader) decompressTar(header ar.Header) *tar.Reader {
 func (pfr *PackageFileReader) processGpgBuilderFile(header ar.Header) {
 	var buff bytes.Buffer
 	defer buff.Reset()
-	_, err := io.Copy(&buff, pfr.arcnt)
+	_, err := io.Copy(&buff, pfr.body)
 	pfr.checkErr(err)
-	pfr.pkg.gpgbuilder = strings.TrimSpace(buff.String())
+	pfr.member.observeBuffer(buff.Len())
+	pfr.pkg.gpgbuilder = NewGpgSignature()
+	if err := pfr.pkg.gpgbuilder.parse(strings.TrimSpace(buff.String())); err != nil {
+		// Signature is not verified anyway, so it does not make the package unreadable
+		pfr.pkg.warnings = append(pfr.pkg.warnings, fmt.Sprintf("cannot parse _gpgbuilder: %v", err))
+	}
 }
 
 // Read data file, extracting the meta-data about its contents
@@ -186,6 +263,9 @@ func (pfr *PackageFileReader) processDataFile(header ar.Header) {
 
 	var databuf bytes.Buffer
 	tarFile := pfr.decompressTar(header)
+	if tarFile == nil {
+		return
+	}
 	for {
 		databuf.Reset()
 		hdr, err := tarFile.Next()
@@ -200,6 +280,8 @@ func (pfr *PackageFileReader) processDataFile(header ar.Header) {
 
 			_, err = io.Copy(&databuf, tarFile)
 			pfr.checkErr(err)
+			pfr.member.observeBuffer(int(pfr.member.bytesDecompressed) + databuf.Len())
+			pfr.pkg.checksumHash = pfr.hash
 			pfr.pkg.SetCalculatedChecksum(hdr.Name, NewBytesChecksum(databuf.Bytes()).SetHash(pfr.hash).Sum())
 		}
 	}
@@ -209,15 +291,19 @@ func (pfr *PackageFileReader) processDataFile(header ar.Header) {
 func (pfr *PackageFileReader) processDebianBinaryFile(header ar.Header) {
 	var buff bytes.Buffer
 	defer buff.Reset()
-	_, err := io.Copy(&buff, pfr.arcnt)
+	_, err := io.Copy(&buff, pfr.body)
 	pfr.checkErr(err)
+	pfr.member.observeBuffer(buff.Len())
 	pfr.pkg.debVersion = strings.TrimSpace(buff.String())
 }
 
 // Read control file, compressed with tar and gzip or xz
-func (pfr *PackageFileReader) processControlFile(header ar.Header) {
+func (pfr *PackageFileReader) processControlFile(header ar.Header) error {
 	var databuf bytes.Buffer
 	tarFile := pfr.decompressTar(header)
+	if tarFile == nil {
+		return nil
+	}
 	for {
 		databuf.Reset()
 		hdr, err := tarFile.Next()
@@ -225,18 +311,18 @@ func (pfr *PackageFileReader) processControlFile(header ar.Header) {
 			break
 		}
 		if pfr.checkErr(err) && hdr.Typeflag == tar.TypeReg {
+			if isMaintainerScript(hdr.Name[2:]) {
+				if err := pfr.processMaintainerScript(*hdr, tarFile); err != nil {
+					return err
+				}
+				continue
+			}
+
 			_, err = io.Copy(&databuf, tarFile)
 			pfr.checkErr(err)
+			pfr.member.observeBuffer(int(pfr.member.bytesDecompressed) + databuf.Len())
 
 			switch hdr.Name[2:] {
-			case "postinst":
-				pfr.pkg.postinst = databuf.String()
-			case "postrm":
-				pfr.pkg.postrm = databuf.String()
-			case "preinst":
-				pfr.pkg.preinst = databuf.String()
-			case "prerm":
-				pfr.pkg.prerm = databuf.String()
 			case "md5sums":
 				pfr.pkg.parseMd5Sums(databuf.Bytes())
 			case "control":
@@ -258,6 +344,42 @@ func (pfr *PackageFileReader) processControlFile(header ar.Header) {
 			}
 		}
 	}
+
+	return nil
+}
+
+// Read maintainer script meta-data and either keep its body or pass it to the script sink
+// Errors of the script sink are returned, as they are not errors of the package.
+func (pfr *PackageFileReader) processMaintainerScript(hdr tar.Header, body io.Reader) error {
+	script := NewMaintainerScript(hdr.Name[2:], hdr)
+	reader := bufio.NewReaderSize(body, maxShebangLength)
+	script.parseInterpreter(reader)
+	pfr.pkg.scripts = append(pfr.pkg.scripts, *script)
+
+	if pfr.scriptSink != nil {
+		if err := pfr.scriptSink(script, reader); err != nil {
+			return fmt.Errorf("script sink failed on %s: %v", script.name, err)
+		}
+		return nil
+	}
+
+	var buff bytes.Buffer
+	_, err := io.Copy(&buff, reader)
+	pfr.checkErr(err)
+	pfr.member.observeBuffer(int(pfr.member.bytesDecompressed) + buff.Len())
+
+	switch script.name {
+	case "postinst":
+		pfr.pkg.postinst = buff.String()
+	case "postrm":
+		pfr.pkg.postrm = buff.String()
+	case "preinst":
+		pfr.pkg.preinst = buff.String()
+	case "prerm":
+		pfr.pkg.prerm = buff.String()
+	}
+
+	return nil
 }
 
 // Read Debian package data from the stream
@@ -274,21 +396,78 @@ func (pfr *PackageFileReader) Read() (*PackageFile, error) {
 			// Yocto's IPK has trailing path for some weird reasons (same format tho)
 			header.Name = path.Base(strings.ReplaceAll(header.Name, "/", ""))
 
-			if strings.HasPrefix(header.Name, "control.") {
-				pfr.processControlFile(*header)
-			} else if strings.HasPrefix(header.Name, "data.") {
+			kind, err := pfr.checkMember(header.Name)
+			if err != nil {
+				return nil, err
+			}
+
+			start := time.Now()
+			pfr.member = NewMemberStats(header.Name, header.Size)
+			pfr.body = &memberReader{reader: pfr.arcnt, stats: pfr.member}
+			switch kind {
+			case memberControl:
+				if err := pfr.processControlFile(*header); err != nil {
+					return nil, err
+				}
+			case memberData:
 				pfr.processDataFile(*header)
-			} else if header.Name == "_gpgbuilder" {
+			case memberGpgBuilder:
 				pfr.processGpgBuilderFile(*header)
-			} else if header.Name == "debian-binary" {
+			case memberDebianBinary:
 				pfr.processDebianBinaryFile(*header)
 			}
+			pfr.member.duration = time.Since(start)
+			pfr.pkg.stats.add(*pfr.member)
+		}
+	}
+
+	if !pfr.seen[memberDebianBinary] {
+		if err := pfr.violation(STRICT_DEBIAN_BINARY, "missing debian-binary member"); err != nil {
+			return nil, err
 		}
 	}
 
 	return pfr.pkg, nil
 }
 
+// Classify the member and check it against the format specification
+func (pfr *PackageFileReader) checkMember(name string) (int, error) {
+	kind, odd := classifyMember(name)
+	if odd {
+		if err := pfr.violation(STRICT_MEMBER_NAMES, "non-standard member name '%s'", name); err != nil {
+			return kind, err
+		}
+	}
+
+	misplaced := false
+	switch kind {
+	case memberDebianBinary:
+		misplaced = len(pfr.seen) > 0
+	case memberControl:
+		misplaced = (len(pfr.seen) > 0 && !pfr.seen[memberDebianBinary]) || pfr.seen[memberData]
+	case memberData:
+		misplaced = !pfr.seen[memberControl]
+	}
+	if misplaced {
+		if err := pfr.violation(STRICT_MEMBER_ORDER, "member '%s' is out of order", name); err != nil {
+			return kind, err
+		}
+	}
+	pfr.seen[kind] = true
+
+	return kind, nil
+}
+
+// Record a format violation as a warning, unless it is strictly checked. Then return it as an error.
+func (pfr *PackageFileReader) violation(flag int, format string, args ...interface{}) error {
+	msg := fmt.Sprintf(format, args...)
+	if pfr.strict&flag != 0 {
+		return fmt.Errorf("invalid package: %s", msg)
+	}
+	pfr.pkg.warnings = append(pfr.pkg.warnings, msg)
+	return nil
+}
+
 // Checksum object computes and returns the SHA256, SHA1 and MD5 checksums
 // encoded in hexadecimal) of the package file.
 //
@@ -323,30 +502,43 @@ func (cs *Checksum) SetHash(hash int) *Checksum {
 	return cs
 }
 
-// Compute checksum for the given hash
-func (cs *Checksum) compute(csType hash.Hash) (string, error) {
+// Write the payload or the file to the writer
+func (cs *Checksum) writeTo(writer io.Writer) error {
 	if cs.payload != nil {
-		if _, err := io.Copy(csType, bytes.NewReader(cs.payload)); err != nil {
-			return "", err
-		}
-	} else {
-		if cs.path == "" {
-			return "", fmt.Errorf("No path has been defined")
-		}
-		f, err := os.Open(cs.path)
-		if err != nil {
-			return "", err
-		}
-		defer f.Close()
+		_, err := io.Copy(writer, bytes.NewReader(cs.payload))
+		return err
+	}
 
-		if _, err := io.Copy(csType, f); err != nil {
-			return "", err
-		}
+	if cs.path == "" {
+		return fmt.Errorf("No path has been defined")
 	}
+	f, err := os.Open(cs.path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	_, err = io.Copy(writer, f)
+	return err
+}
 
+// Compute checksum for the given hash
+func (cs *Checksum) compute(csType hash.Hash) (string, error) {
+	if err := cs.writeTo(csType); err != nil {
+		return "", err
+	}
 	return hex.EncodeToString(csType.Sum(nil)), nil
 }
 
+// Compute MD5, SHA1 and SHA256 checksums at once, reading the data only once
+func (cs *Checksum) computeAll() (md5sum string, sha1sum string, sha256sum string, err error) {
+	md5hash, sha1hash, sha256hash := md5.New(), sha1.New(), sha256.New()
+	if err = cs.writeTo(io.MultiWriter(md5hash, sha1hash, sha256hash)); err != nil {
+		return "", "", "", err
+	}
+	return hex.EncodeToString(md5hash.Sum(nil)), hex.EncodeToString(sha1hash.Sum(nil)), hex.EncodeToString(sha256hash.Sum(nil)), nil
+}
+
 // SHA256 checksum
 func (cs *Checksum) SHA256() string {
 	sum, err := cs.compute(sha256.New())
@@ -375,15 +567,22 @@ func (cs *Checksum) MD5() string {
 }
 
 func (cs *Checksum) Sum() string {
+	sum, err := cs.sum()
+	if err != nil {
+		panic(err)
+	}
+	return sum
+}
+
+// Compute checksum of the hash type that was set
+func (cs *Checksum) sum() (string, error) {
 	switch cs.hash {
-	case HASH_MD5:
-		return cs.MD5()
 	case HASH_SHA1:
-		return cs.SHA1()
+		return cs.compute(sha1.New())
 	case HASH_SHA256:
-		return cs.SHA256()
+		return cs.compute(sha256.New())
 	}
-	return cs.MD5()
+	return cs.compute(md5.New())
 }
 
 // PackageFile object
@@ -397,6 +596,7 @@ type PackageFile struct {
 	prerm    string
 	postinst string
 	postrm   string
+	scripts  []MaintainerScript
 
 	checksum   *Checksum
 	control    *ControlFile
@@ -404,11 +604,14 @@ type PackageFile struct {
 	shlibs     *SharedLibsFile
 	triggers   *TriggerFile
 	conffiles  *CfgFilesFile
-	gpgbuilder string
+	gpgbuilder *GpgSignature
+	stats      *PackageStats
+	warnings   []string
 
 	files                   []FileInfo
 	fileMd5Checksums        map[string]string
 	fileCalculatedChecksums map[string]string
+	checksumHash            int // Hash type of the calculated checksums
 }
 
 // Constructor
@@ -417,11 +620,14 @@ func NewPackageFile() *PackageFile {
 	pf.fileMd5Checksums = make(map[string]string)    // Original dpkg's md5sums. They are always missing configs.
 	pf.fileCalculatedChecksums = map[string]string{} // SHA calculated checksums. Parsing package is slower, if this is on.
 	pf.files = make([]FileInfo, 0)
+	pf.scripts = make([]MaintainerScript, 0)
 	pf.control = NewControlFile()
 	pf.symbols = NewSymbolsFile()
 	pf.shlibs = NewSharedLibsFile()
 	pf.triggers = NewTriggerFile()
 	pf.conffiles = NewCfgFilesFiles()
+	pf.stats = NewPackageStats()
+	pf.warnings = make([]string, 0)
 
 	return pf
 }
@@ -535,24 +741,13 @@ func (c *PackageFile) parseSharedLibsFile(data []byte) {
 
 // Parse control file
 func (c *PackageFile) parseControlFile(data []byte) {
-	var line string
-	var namedata []string
-	var currentName string
-
-	scn := bufio.NewScanner(strings.NewReader(string(data)))
-	for scn.Scan() {
-		// Single field values
-		line = scn.Text()
-		if strings.HasPrefix(strings.TrimSpace(line), "#") {
-			continue
-		}
-
-		if strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t") {
-			c.control.addToField(currentName, line)
-		} else {
-			namedata = strings.SplitN(line, ":", 2)
-			currentName = namedata[0]
-			c.control.setField(namedata...)
+	c.control.raw = append([]byte(nil), data...) // data is a reused buffer
+	fields, _ := splitControlFields(data)        // Malformed lines are skipped
+	for _, field := range fields {
+		lines := strings.Split(field.value, "\n")
+		c.control.setField(field.name, lines[0])
+		for _, line := range lines[1:] {
+			c.control.addToField(field.name, line)
 		}
 	}
 }
@@ -591,6 +786,21 @@ func (c *PackageFile) PostUninstallScript() string {
 	return c.postrm
 }
 
+// Scripts returns meta-data of the maintainer scripts, in order of appearance in the package
+func (c *PackageFile) Scripts() []MaintainerScript {
+	return c.scripts
+}
+
+// Script returns meta-data of the maintainer script by its name (e.g. "postinst") or nil if not present
+func (c *PackageFile) Script(name string) *MaintainerScript {
+	for i := range c.scripts {
+		if c.scripts[i].name == name {
+			return &c.scripts[i]
+		}
+	}
+	return nil
+}
+
 // GetFileMd5Sums returns file checksum by relative path from the md5sums file.
 // NOTE: md5sums file omits configuration files.
 func (c *PackageFile) GetFileMd5Sums(path string) string {
@@ -632,6 +842,22 @@ func (c *PackageFile) ConffilesFile() *CfgFilesFile {
 	return c.conffiles
 }
 
+// GpgBuilder returns parsed _gpgbuilder signature, or nil if the package is not signed.
+// The signature is not verified.
+func (c *PackageFile) GpgBuilder() *GpgSignature {
+	return c.gpgbuilder
+}
+
+// Warnings returns format violations, that were accepted while reading the package
+func (c *PackageFile) Warnings() []string {
+	return c.warnings
+}
+
+// Stats returns parse statistics of the package: sizes, buffers and timings per member.
+func (c *PackageFile) Stats() *PackageStats {
+	return c.stats
+}
+
 // Return meta-content of the package
 func (c *PackageFile) Files() []FileInfo {
 	return c.files
@@ -657,3 +883,8 @@ func (c *PackageFile) SetCalculatedChecksum(path, sum string) *PackageFile {
 func (c *PackageFile) GetCalculatedChecksum(path string) string {
 	return c.fileCalculatedChecksums[path]
 }
+
+// GetCalculatedChecksumHash returns hash type of the calculated checksums, one of HASH_MD5, HASH_SHA1 or HASH_SHA256.
+func (c *PackageFile) GetCalculatedChecksumHash() int {
+	return c.checksumHash
+}
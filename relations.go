@@ -0,0 +1,172 @@
+package deb
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Relation is a single package relation as found in Depends, Build-Depends etc, e.g.:
+//
+//	libfoo-dev:native (>= 1.2) [amd64 !i386] <!nocheck> <cross>
+//
+// Architecture lists and build profile restrictions are only allowed in source paragraphs.
+type Relation struct {
+	name          string
+	archQualifier string
+	operator      string
+	version       string
+	architectures []string
+	profiles      [][]string
+}
+
+// NewRelation constructor
+func NewRelation() *Relation {
+	rel := new(Relation)
+	rel.architectures = make([]string, 0)
+	rel.profiles = make([][]string, 0)
+	return rel
+}
+
+// Name of the package
+func (rel *Relation) Name() string {
+	return rel.name
+}
+
+// ArchQualifier returns a qualifier after the colon, such as "any" or "native".
+func (rel *Relation) ArchQualifier() string {
+	return rel.archQualifier
+}
+
+// Operator returns the version relation operator: <<, <=, =, >= or >>.
+func (rel *Relation) Operator() string {
+	return rel.operator
+}
+
+// Version the relation is restricted to, if any
+func (rel *Relation) Version() string {
+	return rel.version
+}
+
+// Architectures returns an architecture restriction list, e.g. ["amd64", "!i386"]
+func (rel *Relation) Architectures() []string {
+	return rel.architectures
+}
+
+// Profiles returns build profile restriction formulas. The relation applies if any
+// of the formulas match, and a formula matches if all of its terms do, e.g. <!nocheck> <cross>
+// is [["!nocheck"], ["cross"]].
+func (rel *Relation) Profiles() [][]string {
+	return rel.profiles
+}
+
+// String returns relation in the control file notation
+func (rel *Relation) String() string {
+	var b strings.Builder
+	b.WriteString(rel.name)
+	if rel.archQualifier != "" {
+		b.WriteString(":" + rel.archQualifier)
+	}
+	if rel.operator != "" {
+		fmt.Fprintf(&b, " (%s %s)", rel.operator, rel.version)
+	}
+	if len(rel.architectures) > 0 {
+		fmt.Fprintf(&b, " [%s]", strings.Join(rel.architectures, " "))
+	}
+	for _, p := range rel.profiles {
+		fmt.Fprintf(&b, " <%s>", strings.Join(p, " "))
+	}
+	return b.String()
+}
+
+// ParseRelations parses a relation field value. The result is a list of
+// AND-ed entries (comma separated), each being a list of alternatives (pipe separated).
+func ParseRelations(data string) ([][]Relation, error) {
+	relations := make([][]Relation, 0)
+	for _, entry := range strings.Split(data, ",") {
+		if strings.TrimSpace(entry) == "" {
+			continue // trailing commas are common in the wild
+		}
+		alts := make([]Relation, 0)
+		for _, alt := range strings.Split(entry, "|") {
+			rel, err := parseRelation(alt)
+			if err != nil {
+				return nil, err
+			}
+			alts = append(alts, *rel)
+		}
+		relations = append(relations, alts)
+	}
+	return relations, nil
+}
+
+// Parse one relation, without alternatives
+func parseRelation(data string) (*Relation, error) {
+	rel := NewRelation()
+	data = strings.TrimSpace(data)
+	if data == "" {
+		return nil, fmt.Errorf("empty relation")
+	}
+
+	// Package name with optional architecture qualifier ends at first space or bracket
+	end := strings.IndexAny(data, " \t\r\n([<")
+	if end < 0 {
+		end = len(data)
+	}
+	nq := strings.SplitN(data[:end], ":", 2)
+	rel.name = nq[0]
+	if len(nq) == 2 {
+		rel.archQualifier = nq[1]
+	}
+	data = strings.TrimSpace(data[end:])
+
+	for data != "" {
+		var closer byte
+		switch data[0] {
+		case '(':
+			closer = ')'
+		case '[':
+			closer = ']'
+		case '<':
+			closer = '>'
+		default:
+			return nil, fmt.Errorf("unexpected data in relation '%s': %s", rel.name, data)
+		}
+		end = strings.IndexByte(data, closer)
+		if end < 0 {
+			return nil, fmt.Errorf("unterminated '%c' in relation '%s'", data[0], rel.name)
+		}
+		inner := strings.TrimSpace(data[1:end])
+
+		switch data[0] {
+		case '(':
+			if err := rel.setVersion(inner); err != nil {
+				return nil, err
+			}
+		case '[':
+			rel.architectures = append(rel.architectures, strings.Fields(inner)...)
+		case '<':
+			rel.profiles = append(rel.profiles, strings.Fields(inner))
+		}
+		data = strings.TrimSpace(data[end+1:])
+	}
+
+	return rel, nil
+}
+
+// Set version restriction, such as ">= 1.0" or ">=1.0"
+func (rel *Relation) setVersion(data string) error {
+	op := strings.TrimRight(data[:len(data)-len(strings.TrimLeft(data, "<>="))], " ")
+	switch op {
+	case "<<", "<=", "=", ">=", ">>":
+		rel.operator = op
+	case "<", ">": // deprecated forms, which mean <= and >=
+		rel.operator = op + "="
+	default:
+		return fmt.Errorf("invalid version operator '%s' in relation '%s'", op, rel.name)
+	}
+	rel.version = strings.TrimSpace(data[len(op):])
+	if rel.version == "" {
+		return fmt.Errorf("missing version in relation '%s'", rel.name)
+	}
+	return nil
+}
@@ -0,0 +1,131 @@
+package deb
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseRelations(t *testing.T) {
+	tests := []struct {
+		data     string
+		expected [][]string // String() of each relation, grouped by alternatives
+	}{
+		{"", [][]string{}},
+		{"libc6 (>= 2.34)", [][]string{{"libc6 (>= 2.34)"}}},
+		{"libc6 (>=2.34), libgcc-s1(>= 3.0),", [][]string{{"libc6 (>= 2.34)"}, {"libgcc-s1 (>= 3.0)"}}},
+		{"default-mta | mail-transport-agent", [][]string{{"default-mta", "mail-transport-agent"}}},
+		{"foo (> 1), bar (< 2)", [][]string{{"foo (>= 1)"}, {"bar (<= 2)"}}},
+		{"python3:any, gcc-multilib [amd64 i386] <!nocheck>", [][]string{{"python3:any"}, {"gcc-multilib [amd64 i386] <!nocheck>"}}},
+		// systemd
+		{
+			"debhelper-compat (= 13), pkgconf, libcap-dev (>= 1:2.24-9~), libpam0g-dev, libapparmor-dev (>= 2.13) <!stage1>, libselinux1-dev (>= 3.1) <!stage1>, libseccomp-dev (>= 2.3.1) [amd64 arm64 armel armhf i386 mips mipsel mips64 mips64el x32 powerpc ppc64 ppc64el riscv64 s390x] <!stage1>",
+			[][]string{
+				{"debhelper-compat (= 13)"}, {"pkgconf"}, {"libcap-dev (>= 1:2.24-9~)"}, {"libpam0g-dev"},
+				{"libapparmor-dev (>= 2.13) <!stage1>"}, {"libselinux1-dev (>= 3.1) <!stage1>"},
+				{"libseccomp-dev (>= 2.3.1) [amd64 arm64 armel armhf i386 mips mipsel mips64 mips64el x32 powerpc ppc64 ppc64el riscv64 s390x] <!stage1>"},
+			},
+		},
+		// glibc, continuation lines as joined by the paragraph parser
+		{
+			"gettext, dpkg (>= 1.18.7), dpkg-dev (>= 1.17.14), xz-utils, file, quilt,\n autoconf, gawk, debhelper-compat (= 13), rdfind, symlinks, netbase, gperf, bison,\n g++-12 <!nobiarch>, g++-12-multilib [amd64 i386 kfreebsd-amd64 mips mipsel mipsn32 mipsn32el mips64 mips64el mipsr6 mipsr6el mipsn32r6 mipsn32r6el mips64r6 mips64r6el powerpc ppc64 s390x sparc sparc64 x32] <!nobiarch>,\n python3:native <!nocheck>, libc-bin (>= 2.36) <cross>",
+			[][]string{
+				{"gettext"}, {"dpkg (>= 1.18.7)"}, {"dpkg-dev (>= 1.17.14)"}, {"xz-utils"}, {"file"}, {"quilt"},
+				{"autoconf"}, {"gawk"}, {"debhelper-compat (= 13)"}, {"rdfind"}, {"symlinks"}, {"netbase"}, {"gperf"}, {"bison"},
+				{"g++-12 <!nobiarch>"},
+				{"g++-12-multilib [amd64 i386 kfreebsd-amd64 mips mipsel mipsn32 mipsn32el mips64 mips64el mipsr6 mipsr6el mipsn32r6 mipsn32r6el mips64r6 mips64r6el powerpc ppc64 s390x sparc sparc64 x32] <!nobiarch>"},
+				{"python3:native <!nocheck>"}, {"libc-bin (>= 2.36) <cross>"},
+			},
+		},
+		{"libfoo-dev\n (>= 1)", [][]string{{"libfoo-dev (>= 1)"}}},
+		{"gcc <!nocheck !cross> <stage1>", [][]string{{"gcc <!nocheck !cross> <stage1>"}}},
+	}
+
+	for _, tt := range tests {
+		relations, err := ParseRelations(tt.data)
+		if err != nil {
+			t.Errorf("ParseRelations(%q) returned error: %v", tt.data, err)
+			continue
+		}
+		got := make([][]string, 0)
+		for _, alts := range relations {
+			group := make([]string, 0)
+			for _, rel := range alts {
+				group = append(group, rel.String())
+			}
+			got = append(got, group)
+		}
+		if !reflect.DeepEqual(got, tt.expected) {
+			t.Errorf("ParseRelations(%q) = %q, expected %q", tt.data, got, tt.expected)
+		}
+	}
+}
+
+func TestParseRelationFields(t *testing.T) {
+	relations, err := ParseRelations("libc6-dev:native (>= 2.3) [amd64 !i386] <!nocheck> <cross stage1>")
+	if err != nil {
+		t.Fatal(err)
+	}
+	rel := relations[0][0]
+	if rel.Name() != "libc6-dev" || rel.ArchQualifier() != "native" || rel.Operator() != ">=" || rel.Version() != "2.3" {
+		t.Errorf("unexpected relation: %q %q %q %q", rel.Name(), rel.ArchQualifier(), rel.Operator(), rel.Version())
+	}
+	if !reflect.DeepEqual(rel.Architectures(), []string{"amd64", "!i386"}) {
+		t.Errorf("unexpected architectures: %q", rel.Architectures())
+	}
+	if !reflect.DeepEqual(rel.Profiles(), [][]string{{"!nocheck"}, {"cross", "stage1"}}) {
+		t.Errorf("unexpected profiles: %q", rel.Profiles())
+	}
+}
+
+func TestParseRelationsErrors(t *testing.T) {
+	for _, data := range []string{
+		"foo | , bar",
+		"foo (>= 1.0",
+		"foo (1.0)",
+		"foo (>=)",
+		"foo [amd64",
+		"foo <!nocheck",
+		"foo bar",
+	} {
+		if _, err := ParseRelations(data); err == nil {
+			t.Errorf("ParseRelations(%q) expected to fail", data)
+		}
+	}
+}
+
+func TestControlFileRelations(t *testing.T) {
+	p := NewPackageFile()
+	p.parseControlFile([]byte("Package: foo\nPre-Depends: dpkg (>= 1.17.5)\nDepends: libc6 (>= 2.34), bar | baz\n"))
+
+	predepends, err := p.ControlFile().Relations("Pre-Depends")
+	if err != nil || len(predepends) != 1 || predepends[0][0].String() != "dpkg (>= 1.17.5)" {
+		t.Errorf("unexpected Pre-Depends relations: %v %v", predepends, err)
+	}
+	if !reflect.DeepEqual(p.ControlFile().Predepends(), []string{"dpkg (>= 1.17.5)"}) {
+		t.Errorf("unexpected Predepends: %q", p.ControlFile().Predepends())
+	}
+
+	depends, err := p.ControlFile().Relations("depends")
+	if err != nil || len(depends) != 2 || len(depends[1]) != 2 {
+		t.Errorf("unexpected Depends relations: %v %v", depends, err)
+	}
+
+	// Folded over continuation lines, as in the dpkg status database
+	p = NewPackageFile()
+	p.parseControlFile([]byte("Package: foo\nDepends: libc6 (>= 2.34),\n bar | baz,\n\tqux\nRecommends:\n foo-doc\n"))
+	depends, err = p.ControlFile().Relations("depends")
+	if err != nil || len(depends) != 3 || len(depends[1]) != 2 || depends[2][0].Name() != "qux" {
+		t.Errorf("unexpected folded Depends relations: %v %v", depends, err)
+	}
+	if !reflect.DeepEqual(p.ControlFile().Depends(), []string{"libc6 (>= 2.34)", "bar", "baz", "qux"}) {
+		t.Errorf("unexpected folded Depends: %q", p.ControlFile().Depends())
+	}
+	recommends, err := p.ControlFile().Relations("recommends")
+	if err != nil || len(recommends) != 1 || recommends[0][0].Name() != "foo-doc" {
+		t.Errorf("unexpected folded Recommends relations: %v %v", recommends, err)
+	}
+
+	if _, err := p.ControlFile().Relations("Package"); err == nil {
+		t.Error("Relations of a non-relation field expected to fail")
+	}
+}
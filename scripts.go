@@ -0,0 +1,78 @@
+package deb
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"io"
+	"os"
+	"strings"
+)
+
+// Longest shebang line that is looked at for the interpreter
+const maxShebangLength = 256
+
+// ScriptSink receives the body of a maintainer script while the package is read.
+// If set, script bodies are not retained in the PackageFile.
+//
+// Note that the control archive is still decompressed into memory as a whole,
+// so the sink saves only the copies of the scripts kept afterwards, not the peak memory use.
+type ScriptSink func(script *MaintainerScript, body io.Reader) error
+
+// MaintainerScript describes a maintainer script (preinst, postinst, prerm or postrm)
+// in the control archive of the package.
+type MaintainerScript struct {
+	name        string
+	size        int64
+	mode        os.FileMode
+	interpreter string
+}
+
+// NewMaintainerScript constructor
+func NewMaintainerScript(name string, header tar.Header) *MaintainerScript {
+	ms := new(MaintainerScript)
+	ms.name = name
+	ms.size = header.Size
+	ms.mode = header.FileInfo().Mode()
+	return ms
+}
+
+// Check if a control archive member is a maintainer script
+func isMaintainerScript(name string) bool {
+	return in(name, []string{"preinst", "postinst", "prerm", "postrm"})
+}
+
+// Set interpreter from the shebang line, without consuming the reader
+func (ms *MaintainerScript) parseInterpreter(reader *bufio.Reader) {
+	head, _ := reader.Peek(maxShebangLength) // short scripts are fine
+	if !bytes.HasPrefix(head, []byte("#!")) {
+		return
+	}
+	if eol := bytes.IndexByte(head, '\n'); eol > -1 {
+		head = head[:eol]
+	}
+	if fields := strings.Fields(string(head[2:])); len(fields) > 0 {
+		ms.interpreter = fields[0]
+	}
+}
+
+// Name of the script, e.g. "postinst"
+func (ms *MaintainerScript) Name() string {
+	return ms.name
+}
+
+// Size of the script in bytes
+func (ms *MaintainerScript) Size() int64 {
+	return ms.size
+}
+
+// Mode is the file mode of the script
+func (ms *MaintainerScript) Mode() os.FileMode {
+	return ms.mode
+}
+
+// Interpreter returns the interpreter from the shebang line, e.g. "/bin/sh".
+// It is empty if the script has no shebang (and would fail to run in dpkg anyway).
+func (ms *MaintainerScript) Interpreter() string {
+	return ms.interpreter
+}
@@ -0,0 +1,90 @@
+package deb
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"io/ioutil"
+	"strings"
+	"testing"
+)
+
+func TestParseInterpreter(t *testing.T) {
+	for _, tt := range []struct {
+		body        string
+		interpreter string
+	}{
+		{"#!/bin/sh\nset -e\n", "/bin/sh"},
+		{"#! /bin/bash -e\n", "/bin/bash"},
+		{"#!/usr/bin/perl -w", "/usr/bin/perl"}, // No newline
+		{"#!/bin/sh\r\n", "/bin/sh"},
+		{"#!\n/bin/sh\n", ""},
+		{"set -e\n", ""},
+		{"", ""},
+		{" #!/bin/sh\n", ""},
+		{"#!/bin/" + strings.Repeat("x", maxShebangLength), "/bin/" + strings.Repeat("x", maxShebangLength-len("#!/bin/"))},
+	} {
+		ms := new(MaintainerScript)
+		reader := bufio.NewReaderSize(strings.NewReader(tt.body), maxShebangLength)
+		ms.parseInterpreter(reader)
+		if ms.Interpreter() != tt.interpreter {
+			t.Errorf("parseInterpreter(%q) = %q, expected %q", tt.body, ms.Interpreter(), tt.interpreter)
+		}
+		if body, _ := ioutil.ReadAll(reader); string(body) != tt.body {
+			t.Errorf("parseInterpreter(%q) consumed the body", tt.body)
+		}
+	}
+}
+
+func TestMaintainerScripts(t *testing.T) {
+	postinst := "#!/bin/sh\nset -e\necho configured\n"
+	prerm := "#!/usr/bin/perl\nexit 0;\n"
+	data := testDeb(testControl("",
+		testEntry{name: "./postinst", body: postinst, mode: 0755},
+		testEntry{name: "./prerm", body: prerm, mode: 0755},
+	), []testEntry{{name: "./"}})
+
+	p, err := testRead(data, DefaultPackageOptions)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.PostInstallScript() != postinst || p.PreUninstallScript() != prerm || p.PreInstallScript() != "" {
+		t.Errorf("unexpected script bodies: %q %q %q", p.PostInstallScript(), p.PreUninstallScript(), p.PreInstallScript())
+	}
+	if len(p.Scripts()) != 2 || p.Scripts()[0].Name() != "postinst" || p.Scripts()[1].Name() != "prerm" {
+		t.Errorf("unexpected scripts: %v", p.Scripts())
+	}
+	script := p.Script("prerm")
+	if script == nil || script.Size() != int64(len(prerm)) || script.Mode().Perm() != 0755 || script.Interpreter() != "/usr/bin/perl" {
+		t.Errorf("unexpected prerm script: %+v", script)
+	}
+	if p.Script("preinst") != nil {
+		t.Error("Script of a missing script expected to be nil")
+	}
+
+	// Sink gets the whole bodies, the package keeps meta-data only
+	bodies := make(map[string]string)
+	sink := func(script *MaintainerScript, body io.Reader) error {
+		data, err := ioutil.ReadAll(body)
+		bodies[script.Name()] = string(data)
+		return err
+	}
+	p, err = testRead(data, &PackageOptions{ScriptSink: sink})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bodies["postinst"] != postinst || bodies["prerm"] != prerm || p.PostInstallScript() != "" {
+		t.Errorf("unexpected sink bodies %q, package body %q", bodies, p.PostInstallScript())
+	}
+	if script := p.Script("postinst"); script == nil || script.Interpreter() != "/bin/sh" || script.Size() != int64(len(postinst)) {
+		t.Errorf("unexpected postinst script: %+v", script)
+	}
+
+	// Sink errors are returned, not panicked
+	failing := func(script *MaintainerScript, body io.Reader) error {
+		return errors.New("disk full")
+	}
+	if _, err := testRead(data, &PackageOptions{ScriptSink: failing}); err == nil || !strings.Contains(err.Error(), "disk full") {
+		t.Errorf("expected sink error, got %v", err)
+	}
+}
@@ -0,0 +1,241 @@
+package deb
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+// SourceControlFile is a source package paragraph, as found in .dsc files
+// or in the Sources index of a repository.
+type SourceControlFile struct {
+	format           string
+	src              string
+	version          string
+	maintainer       string
+	homepage         string
+	standardsVersion string
+	section          string
+	priority         string
+	directory        string
+	testsuite        string
+	binary           []string
+	arch             []string
+	uploaders        []string
+
+	buildDepends        [][]Relation
+	buildDependsIndep   [][]Relation
+	buildDependsArch    [][]Relation
+	buildConflicts      [][]Relation
+	buildConflictsIndep [][]Relation
+	buildConflictsArch  [][]Relation
+
+	// Everything else (Vcs-*, Checksums-*, Files etc) is kept as is, by lowercase name
+	fields map[string]string
+}
+
+// NewSourceControlFile constructor
+func NewSourceControlFile() *SourceControlFile {
+	scf := new(SourceControlFile)
+	scf.binary = make([]string, 0)
+	scf.arch = make([]string, 0)
+	scf.uploaders = make([]string, 0)
+	scf.fields = make(map[string]string)
+
+	return scf
+}
+
+// ParseSourceControlFile parses a single source paragraph, e.g. a .dsc file.
+// OpenPGP clearsign armor, if present, is skipped.
+func ParseSourceControlFile(data []byte) (*SourceControlFile, error) {
+	scf := NewSourceControlFile()
+	if err := scf.parse(data); err != nil {
+		return nil, err
+	}
+	return scf, nil
+}
+
+// ParseSourcesIndex parses all paragraphs of a repository Sources index.
+func ParseSourcesIndex(data []byte) ([]*SourceControlFile, error) {
+	sources := make([]*SourceControlFile, 0)
+	for _, paragraph := range bytes.Split(bytes.ReplaceAll(data, []byte("\r\n"), []byte("\n")), []byte("\n\n")) {
+		if len(bytes.TrimSpace(paragraph)) == 0 {
+			continue
+		}
+		scf, err := ParseSourceControlFile(paragraph)
+		if err != nil {
+			return nil, err
+		}
+		sources = append(sources, scf)
+	}
+	return sources, nil
+}
+
+// Parse paragraph, joining continuation lines of multiline fields
+func (scf *SourceControlFile) parse(data []byte) error {
+	fields, err := splitControlFields(data)
+	if err != nil {
+		return err
+	}
+	for _, field := range fields {
+		lines := strings.Split(field.value, "\n")
+		for i := range lines {
+			lines[i] = strings.TrimSpace(lines[i])
+		}
+		if err := scf.setField(strings.ToLower(field.name), strings.Join(lines, "\n")); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Set field by its lowercase name
+func (scf *SourceControlFile) setField(name string, data string) error {
+	var err error
+	data = strings.TrimSpace(data)
+	switch name {
+	case "format":
+		scf.format = data
+	case "source", "package": // Sources index calls it "Package"
+		scf.src = data
+	case "version":
+		scf.version = data
+	case "maintainer":
+		scf.maintainer = data
+	case "homepage":
+		scf.homepage = data
+	case "standards-version":
+		scf.standardsVersion = data
+	case "section":
+		scf.section = data
+	case "priority":
+		scf.priority = data
+	case "directory":
+		scf.directory = data
+	case "testsuite":
+		scf.testsuite = data
+	case "binary":
+		scf.binary = splitList(data)
+	case "architecture":
+		scf.arch = strings.Fields(data)
+	case "uploaders":
+		scf.uploaders = splitList(data)
+	case "build-depends":
+		scf.buildDepends, err = ParseRelations(data)
+	case "build-depends-indep":
+		scf.buildDependsIndep, err = ParseRelations(data)
+	case "build-depends-arch":
+		scf.buildDependsArch, err = ParseRelations(data)
+	case "build-conflicts":
+		scf.buildConflicts, err = ParseRelations(data)
+	case "build-conflicts-indep":
+		scf.buildConflictsIndep, err = ParseRelations(data)
+	case "build-conflicts-arch":
+		scf.buildConflictsArch, err = ParseRelations(data)
+	default:
+		scf.fields[name] = data
+	}
+
+	if err != nil {
+		return fmt.Errorf("field %s: %v", name, err)
+	}
+	return nil
+}
+
+// Split comma separated list, dropping empty elements
+func splitList(data string) []string {
+	list := make([]string, 0)
+	for _, v := range strings.Split(data, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			list = append(list, v)
+		}
+	}
+	return list
+}
+
+// Format of the source package, e.g. "3.0 (quilt)"
+func (scf *SourceControlFile) Format() string {
+	return scf.format
+}
+
+// Source package name
+func (scf *SourceControlFile) Source() string {
+	return scf.src
+}
+
+func (scf *SourceControlFile) Version() string {
+	return scf.version
+}
+
+func (scf *SourceControlFile) Maintainer() string {
+	return scf.maintainer
+}
+
+func (scf *SourceControlFile) Uploaders() []string {
+	return scf.uploaders
+}
+
+func (scf *SourceControlFile) Homepage() string {
+	return scf.homepage
+}
+
+func (scf *SourceControlFile) StandardsVersion() string {
+	return scf.standardsVersion
+}
+
+func (scf *SourceControlFile) Section() string {
+	return scf.section
+}
+
+func (scf *SourceControlFile) Priority() string {
+	return scf.priority
+}
+
+// Directory of the source package in the repository pool (Sources index only)
+func (scf *SourceControlFile) Directory() string {
+	return scf.directory
+}
+
+func (scf *SourceControlFile) Testsuite() string {
+	return scf.testsuite
+}
+
+// Binary returns names of the binary packages built from this source
+func (scf *SourceControlFile) Binary() []string {
+	return scf.binary
+}
+
+// Architecture returns the list of architectures, e.g. ["any", "all"]
+func (scf *SourceControlFile) Architecture() []string {
+	return scf.arch
+}
+
+func (scf *SourceControlFile) BuildDepends() [][]Relation {
+	return scf.buildDepends
+}
+
+func (scf *SourceControlFile) BuildDependsIndep() [][]Relation {
+	return scf.buildDependsIndep
+}
+
+func (scf *SourceControlFile) BuildDependsArch() [][]Relation {
+	return scf.buildDependsArch
+}
+
+func (scf *SourceControlFile) BuildConflicts() [][]Relation {
+	return scf.buildConflicts
+}
+
+func (scf *SourceControlFile) BuildConflictsIndep() [][]Relation {
+	return scf.buildConflictsIndep
+}
+
+func (scf *SourceControlFile) BuildConflictsArch() [][]Relation {
+	return scf.buildConflictsArch
+}
+
+// Field returns raw value of any other field by its name (case insensitive),
+// such as "Vcs-Git" or "Checksums-Sha256".
+func (scf *SourceControlFile) Field(name string) string {
+	return scf.fields[strings.ToLower(name)]
+}
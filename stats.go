@@ -0,0 +1,145 @@
+package deb
+
+import (
+	"io"
+	"time"
+)
+
+// MemberStats are parse statistics of a single ar member of the package,
+// such as "control.tar.gz" or "data.tar.xz".
+type MemberStats struct {
+	name              string
+	size              int64
+	bytesRead         int64
+	bytesDecompressed int64
+	peakBuffer        int64
+	duration          time.Duration
+}
+
+// NewMemberStats constructor
+func NewMemberStats(name string, size int64) *MemberStats {
+	ms := new(MemberStats)
+	ms.name = name
+	ms.size = size
+	return ms
+}
+
+// Reader of the member data, counting the bytes read into the member statistics
+type memberReader struct {
+	reader io.Reader
+	stats  *MemberStats
+}
+
+func (mr *memberReader) Read(p []byte) (int, error) {
+	n, err := mr.reader.Read(p)
+	mr.stats.bytesRead += int64(n)
+	return n, err
+}
+
+// Keep the largest buffer size seen so far
+func (ms *MemberStats) observeBuffer(size int) {
+	if int64(size) > ms.peakBuffer {
+		ms.peakBuffer = int64(size)
+	}
+}
+
+// Name of the member
+func (ms *MemberStats) Name() string {
+	return ms.name
+}
+
+// Size of the member as declared in its ar header
+func (ms *MemberStats) Size() int64 {
+	return ms.size
+}
+
+// BytesRead returns the number of bytes of the member actually read. It is zero for members that were
+// skipped, e.g. the data archive with MetaOnly, and can be less than Size() for truncated packages.
+func (ms *MemberStats) BytesRead() int64 {
+	return ms.bytesRead
+}
+
+// BytesDecompressed returns the size of the decompressed tarball,
+// or zero if the member is not compressed or was skipped.
+func (ms *MemberStats) BytesDecompressed() int64 {
+	return ms.bytesDecompressed
+}
+
+// PeakBuffer returns the approximate largest amount of memory in bytes,
+// held in the buffers at once while processing the member.
+func (ms *MemberStats) PeakBuffer() int64 {
+	return ms.peakBuffer
+}
+
+// Duration of processing the member
+func (ms *MemberStats) Duration() time.Duration {
+	return ms.duration
+}
+
+// PackageStats are parse statistics of the whole package
+type PackageStats struct {
+	members []MemberStats
+}
+
+// NewPackageStats constructor
+func NewPackageStats() *PackageStats {
+	ps := new(PackageStats)
+	ps.members = make([]MemberStats, 0)
+	return ps
+}
+
+func (ps *PackageStats) add(ms MemberStats) {
+	ps.members = append(ps.members, ms)
+}
+
+// Members returns statistics per member, in the order of appearance in the package
+func (ps *PackageStats) Members() []MemberStats {
+	return ps.members
+}
+
+// Size returns the total declared size of all members
+func (ps *PackageStats) Size() int64 {
+	var total int64
+	for _, ms := range ps.members {
+		total += ms.size
+	}
+	return total
+}
+
+// BytesRead returns the total number of bytes read from all members
+func (ps *PackageStats) BytesRead() int64 {
+	var total int64
+	for _, ms := range ps.members {
+		total += ms.bytesRead
+	}
+	return total
+}
+
+// BytesDecompressed returns the total size of all decompressed tarballs
+func (ps *PackageStats) BytesDecompressed() int64 {
+	var total int64
+	for _, ms := range ps.members {
+		total += ms.bytesDecompressed
+	}
+	return total
+}
+
+// PeakBuffer returns the largest peak buffer size among the members
+func (ps *PackageStats) PeakBuffer() int64 {
+	var peak int64
+	for _, ms := range ps.members {
+		if ms.peakBuffer > peak {
+			peak = ms.peakBuffer
+		}
+	}
+	return peak
+}
+
+// Duration returns the total time spent on processing the members
+func (ps *PackageStats) Duration() time.Duration {
+	var total time.Duration
+	for _, ms := range ps.members {
+		total += ms.duration
+	}
+	return total
+}
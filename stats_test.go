@@ -0,0 +1,50 @@
+package deb
+
+import "testing"
+
+func TestPackageStats(t *testing.T) {
+	data := testDeb(testControl(""), []testEntry{{name: "./"}, {name: "./usr/"}, {name: "./usr/foo", body: "hello world\n"}})
+
+	for _, tt := range []struct {
+		metaonly bool
+		read     []bool // Whether member data is expected to be read, in order of members
+	}{
+		{false, []bool{true, true, true}},
+		{true, []bool{true, true, false}},
+	} {
+		p, err := testRead(data, &PackageOptions{MetaOnly: tt.metaonly})
+		if err != nil {
+			t.Fatal(err)
+		}
+		members := p.Stats().Members()
+		if len(members) != 3 {
+			t.Fatalf("expected 3 members, got %d", len(members))
+		}
+
+		var size int64
+		for i, ms := range members {
+			size += ms.Size()
+			if ms.Size() == 0 {
+				t.Errorf("MetaOnly %v: member %s has no size", tt.metaonly, ms.Name())
+			}
+			switch {
+			case tt.read[i] && ms.BytesRead() != ms.Size():
+				t.Errorf("MetaOnly %v: member %s read %d bytes of %d", tt.metaonly, ms.Name(), ms.BytesRead(), ms.Size())
+			case !tt.read[i] && (ms.BytesRead() != 0 || ms.BytesDecompressed() != 0):
+				t.Errorf("MetaOnly %v: skipped member %s read %d bytes, decompressed %d", tt.metaonly, ms.Name(), ms.BytesRead(), ms.BytesDecompressed())
+			}
+			if tt.read[i] && ms.PeakBuffer() < ms.BytesRead() {
+				t.Errorf("MetaOnly %v: member %s peak buffer %d is less than its data %d", tt.metaonly, ms.Name(), ms.PeakBuffer(), ms.BytesRead())
+			}
+		}
+		if members[1].BytesDecompressed() == 0 || (!tt.metaonly && members[2].BytesDecompressed() == 0) {
+			t.Errorf("MetaOnly %v: tarballs were not counted as decompressed", tt.metaonly)
+		}
+		if p.Stats().Size() != size {
+			t.Errorf("MetaOnly %v: total size %d, expected %d", tt.metaonly, p.Stats().Size(), size)
+		}
+		if tt.metaonly && p.Stats().BytesRead() != members[0].Size()+members[1].Size() {
+			t.Errorf("MetaOnly %v: total bytes read %d", tt.metaonly, p.Stats().BytesRead())
+		}
+	}
+}
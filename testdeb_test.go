@@ -0,0 +1,101 @@
+package deb
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"time"
+
+	"github.com/blakesmith/ar"
+)
+
+// Entry of a tar archive built for tests. Directories have names ending with "/",
+// symlinks have a link target.
+type testEntry struct {
+	name string
+	body string
+	link string
+	mode int64
+}
+
+// Build tar archive of the entries
+func testTar(entries ...testEntry) []byte {
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	for _, e := range entries {
+		hdr := &tar.Header{Name: e.name, Mode: e.mode, ModTime: time.Unix(1700000000, 0), Uname: "root", Gname: "root", Format: tar.FormatGNU}
+		switch {
+		case e.link != "":
+			hdr.Typeflag, hdr.Linkname = tar.TypeSymlink, e.link
+		case e.name[len(e.name)-1] == '/':
+			hdr.Typeflag = tar.TypeDir
+		default:
+			hdr.Typeflag, hdr.Size = tar.TypeReg, int64(len(e.body))
+		}
+		if hdr.Mode == 0 {
+			hdr.Mode = 0644
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			panic(err)
+		}
+		if _, err := tw.Write([]byte(e.body)); err != nil {
+			panic(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		panic(err)
+	}
+	return buf.Bytes()
+}
+
+// Gzip the data
+func testGzip(data []byte) []byte {
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	zw.Write(data)
+	zw.Close()
+	return buf.Bytes()
+}
+
+// Member of an ar archive built for tests
+type testMember struct {
+	name string
+	data []byte
+}
+
+// Build ar archive of the members
+func testAr(members ...testMember) []byte {
+	var buf bytes.Buffer
+	aw := ar.NewWriter(&buf)
+	if err := aw.WriteGlobalHeader(); err != nil {
+		panic(err)
+	}
+	for _, m := range members {
+		if err := aw.WriteHeader(&ar.Header{Name: m.name, Mode: 0644, Size: int64(len(m.data)), ModTime: time.Unix(1700000000, 0)}); err != nil {
+			panic(err)
+		}
+		if _, err := aw.Write(m.data); err != nil {
+			panic(err)
+		}
+	}
+	return buf.Bytes()
+}
+
+// Build a package with gzipped control and data archives in the standard member order
+func testDeb(control []testEntry, data []testEntry) []byte {
+	return testAr(
+		testMember{"debian-binary", []byte("2.0\n")},
+		testMember{"control.tar.gz", testGzip(testTar(control...))},
+		testMember{"data.tar.gz", testGzip(testTar(data...))},
+	)
+}
+
+// Control archive entries of a minimal package
+func testControl(fields string, extra ...testEntry) []testEntry {
+	return append([]testEntry{{name: "./"}, {name: "./control", body: "Package: foo\nVersion: 1.0-1\nArchitecture: amd64\n" + fields}}, extra...)
+}
+
+// Read package from memory
+func testRead(data []byte, opts *PackageOptions) (*PackageFile, error) {
+	return newOptionsReader(bytes.NewReader(data), opts).Read()
+}
@@ -0,0 +1,181 @@
+package deb
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Verifier checks whether a file of an installed package matches the expected digest.
+// The path is absolute as the package would install it, e.g. "/usr/bin/foo",
+// and the hash is one of HASH_MD5, HASH_SHA1 or HASH_SHA256.
+//
+// Implement it to verify against remote agents, container layers,
+// stored baselines etc. LocalVerifier checks the local filesystem.
+type Verifier interface {
+	Verify(path string, digest string, hash int) (bool, error)
+}
+
+// LocalVerifier verifies files on the local filesystem under a root directory.
+// Paths and symlinks are resolved within the root, so they cannot point out of it.
+type LocalVerifier struct {
+	root string
+}
+
+// NewLocalVerifier constructor. Root is the directory the package is installed in,
+// e.g. "/" or a mounted image.
+func NewLocalVerifier(root string) *LocalVerifier {
+	lv := new(LocalVerifier)
+	lv.root = root
+	return lv
+}
+
+// Maximum number of symlinks followed while resolving a path, same as Linux does
+const maxSymlinks = 40
+
+// Resolve path on the filesystem under root, as if root was "/": absolute symlinks
+// are followed relative to root and nothing can point out of it. Paths with ".." are rejected.
+func (lv *LocalVerifier) resolve(name string) (string, error) {
+	for _, elem := range strings.Split(name, "/") {
+		if elem == ".." {
+			return "", fmt.Errorf("path %s escapes the root", name)
+		}
+	}
+
+	resolved, pending, links := "/", strings.Split(name, "/"), 0
+	for len(pending) > 0 {
+		elem := pending[0]
+		pending = pending[1:]
+		switch elem {
+		case "", ".":
+			continue
+		case "..": // Only from symlink targets, cannot go above "/"
+			resolved = path.Dir(resolved)
+			continue
+		}
+
+		next := path.Join(resolved, elem)
+		fullpath := filepath.Join(lv.root, filepath.FromSlash(next))
+		fi, err := os.Lstat(fullpath)
+		if err != nil {
+			return "", err
+		}
+		if fi.Mode()&os.ModeSymlink == 0 {
+			resolved = next
+			continue
+		}
+
+		if links++; links > maxSymlinks {
+			return "", fmt.Errorf("too many levels of symbolic links in %s", name)
+		}
+		target, err := os.Readlink(fullpath)
+		if err != nil {
+			return "", err
+		}
+		if path.IsAbs(target) {
+			resolved = "/"
+		}
+		pending = append(strings.Split(target, "/"), pending...)
+	}
+
+	return filepath.Join(lv.root, filepath.FromSlash(resolved)), nil
+}
+
+// Verify file against the digest
+func (lv *LocalVerifier) Verify(name string, digest string, hash int) (bool, error) {
+	switch hash {
+	case HASH_MD5, HASH_SHA1, HASH_SHA256:
+	default:
+		return false, fmt.Errorf("unknown hash: %d", hash)
+	}
+	fullpath, err := lv.resolve(name)
+	if err != nil {
+		return false, err
+	}
+	fi, err := os.Stat(fullpath)
+	if err != nil {
+		return false, err
+	}
+	if !fi.Mode().IsRegular() {
+		return false, nil
+	}
+
+	sum, err := NewChecksum(fullpath).SetHash(hash).sum()
+	if err != nil {
+		return false, err
+	}
+	return strings.EqualFold(sum, digest), nil
+}
+
+// VerifyResult is an outcome of a verification of a single file
+type VerifyResult struct {
+	path   string
+	digest string
+	hash   int
+	ok     bool
+	err    error
+}
+
+// Path of the verified file
+func (vr *VerifyResult) Path() string {
+	return vr.path
+}
+
+// Digest that was expected
+func (vr *VerifyResult) Digest() string {
+	return vr.digest
+}
+
+// Hash type of the digest
+func (vr *VerifyResult) Hash() int {
+	return vr.hash
+}
+
+// OK returns true if the file matches the digest
+func (vr *VerifyResult) OK() bool {
+	return vr.ok
+}
+
+// Err returns an error, occurred during the verification (e.g. missing file)
+func (vr *VerifyResult) Err() error {
+	return vr.err
+}
+
+// Make absolute installation path out of the path in the package, e.g. "./usr/bin/foo"
+func installPath(name string) string {
+	return "/" + strings.TrimPrefix(strings.TrimPrefix(name, "."), "/")
+}
+
+// Verify all regular files of the package with the verifier. Calculated checksums are used,
+// if the package was read with its data. Otherwise md5sums of the package are used,
+// which usually do not cover configuration files.
+func (c *PackageFile) Verify(verifier Verifier) []VerifyResult {
+	results := make([]VerifyResult, 0)
+	check := func(name string, digest string, hash int) {
+		vr := VerifyResult{path: installPath(name), digest: digest, hash: hash}
+		vr.ok, vr.err = verifier.Verify(vr.path, digest, hash)
+		results = append(results, vr)
+	}
+
+	if len(c.fileCalculatedChecksums) > 0 {
+		for _, fi := range c.files {
+			if digest, ok := c.fileCalculatedChecksums[fi.name]; ok {
+				check(fi.name, digest, c.checksumHash)
+			}
+		}
+	} else {
+		names := make([]string, 0, len(c.fileMd5Checksums))
+		for name := range c.fileMd5Checksums {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			check(name, c.fileMd5Checksums[name], HASH_MD5)
+		}
+	}
+
+	return results
+}
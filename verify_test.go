@@ -0,0 +1,149 @@
+package deb
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// Make a root directory with files and symlinks, some of them pointing out of the root
+func testVerifierRoot(t *testing.T) (string, func()) {
+	dir, err := ioutil.TempDir("", "go-deb")
+	if err != nil {
+		t.Fatal(err)
+	}
+	root := filepath.Join(dir, "root")
+	for _, name := range []string{"root/etc", "root/usr/bin", "root/usr/lib", "outside"} {
+		if err := os.MkdirAll(filepath.Join(dir, name), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for name, body := range map[string]string{
+		"root/etc/passwd":  "inside\n",
+		"root/usr/bin/foo": "foo\n",
+		"outside/secret":   "outside\n",
+	} {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(body), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for name, target := range map[string]string{
+		"root/bin":            "usr/bin",                   // Relative, like merged /usr
+		"root/usr/bin/bar":    "/usr/bin/foo",              // Absolute, within root
+		"root/usr/bin/passwd": "/etc/passwd",               // Absolute, would be the host file outside root
+		"root/usr/lib/secret": "../../../outside/secret",   // Relative, escaping root
+		"root/usr/lib/deep":   "../../../../../etc/passwd", // Relative, way above "/"
+		"root/usr/lib/root":   "/",                         // Root itself
+		"root/usr/lib/loop1":  "loop2",
+		"root/usr/lib/loop2":  "loop1",
+		"root/usr/lib/dir":    "../bin/",
+	} {
+		if err := os.Symlink(target, filepath.Join(dir, name)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return root, func() { os.RemoveAll(dir) }
+}
+
+func TestLocalVerifierResolve(t *testing.T) {
+	root, cleanup := testVerifierRoot(t)
+	defer cleanup()
+	lv := NewLocalVerifier(root)
+
+	for _, tt := range []struct {
+		name     string
+		resolved string // Relative to root, empty if an error is expected
+	}{
+		{"/usr/bin/foo", "usr/bin/foo"},
+		{"usr/bin/foo", "usr/bin/foo"},
+		{"/./usr//bin/foo", "usr/bin/foo"},
+		{"/bin/foo", "usr/bin/foo"},
+		{"/usr/bin/bar", "usr/bin/foo"},
+		{"/bin/bar", "usr/bin/foo"},
+		{"/usr/bin/passwd", "etc/passwd"},
+		{"/usr/lib/deep", "etc/passwd"},
+		{"/usr/lib/root/etc/passwd", "etc/passwd"},
+		{"/usr/lib/dir/foo", "usr/bin/foo"},
+		{"/usr/lib/secret", ""},
+		{"/usr/lib/loop1", ""},
+		{"/../outside/secret", ""},
+		{"/usr/../../outside/secret", ""},
+		{"/usr/bin/missing", ""},
+	} {
+		resolved, err := lv.resolve(tt.name)
+		switch {
+		case tt.resolved == "" && err == nil:
+			t.Errorf("resolve(%q) = %q, expected error", tt.name, resolved)
+		case tt.resolved != "" && err != nil:
+			t.Errorf("resolve(%q) failed: %v", tt.name, err)
+		case tt.resolved != "" && resolved != filepath.Join(root, filepath.FromSlash(tt.resolved)):
+			t.Errorf("resolve(%q) = %q, expected %q", tt.name, resolved, tt.resolved)
+		}
+	}
+}
+
+func TestLocalVerifierVerify(t *testing.T) {
+	root, cleanup := testVerifierRoot(t)
+	defer cleanup()
+	lv := NewLocalVerifier(root)
+
+	for _, tt := range []struct {
+		name   string
+		digest string
+		hash   int
+		ok     bool
+		err    bool
+	}{
+		{"/usr/bin/foo", "d3b07384d113edec49eaa6238ad5ff00", HASH_MD5, true, false},
+		{"/usr/bin/foo", "D3B07384D113EDEC49EAA6238AD5FF00", HASH_MD5, true, false},
+		{"/bin/bar", "f1d2d2f924e986ac86fdf7b36c94bcdf32beec15", HASH_SHA1, true, false},
+		{"/usr/bin/foo", "b5bb9d8014a0f9b1d61e21e796d78dccdf1352f23cd32812f4850b878ae4944c", HASH_SHA256, true, false},
+		{"/usr/bin/foo", "00000000000000000000000000000000", HASH_MD5, false, false},
+		{"/usr/bin", "d3b07384d113edec49eaa6238ad5ff00", HASH_MD5, false, false}, // Not a regular file
+		{"/usr/lib/secret", "d3b07384d113edec49eaa6238ad5ff00", HASH_MD5, false, true},
+		{"/usr/bin/foo", "d3b07384d113edec49eaa6238ad5ff00", 42, false, true},
+	} {
+		ok, err := lv.Verify(tt.name, tt.digest, tt.hash)
+		if ok != tt.ok || (err != nil) != tt.err {
+			t.Errorf("Verify(%q, %q, %d) = %v, %v", tt.name, tt.digest, tt.hash, ok, err)
+		}
+	}
+}
+
+func TestPackageFileVerify(t *testing.T) {
+	root, cleanup := testVerifierRoot(t)
+	defer cleanup()
+
+	md5sums := "d3b07384d113edec49eaa6238ad5ff00  usr/bin/foo\n" + fmt.Sprintf("%032x  usr/bin/bar\n", 0) + "d3b07384d113edec49eaa6238ad5ff00  usr/bin/baz\n"
+	data := testDeb(testControl("", testEntry{name: "./md5sums", body: md5sums}),
+		[]testEntry{{name: "./"}, {name: "./usr/"}, {name: "./usr/bin/"}, {name: "./usr/bin/foo", body: "foo\n"}, {name: "./usr/bin/bar", link: "foo"}})
+
+	for _, metaonly := range []bool{false, true} {
+		p, err := testRead(data, &PackageOptions{MetaOnly: metaonly})
+		if err != nil {
+			t.Fatal(err)
+		}
+		results := p.Verify(NewLocalVerifier(root))
+		status := make(map[string]string)
+		for _, vr := range results {
+			switch {
+			case vr.Err() != nil:
+				status[vr.Path()] = "error"
+			case vr.OK():
+				status[vr.Path()] = "ok"
+			default:
+				status[vr.Path()] = "changed"
+			}
+		}
+
+		expected := map[string]string{"/usr/bin/foo": "ok"} // Calculated checksums cover regular files only
+		if metaonly {
+			expected = map[string]string{"/usr/bin/foo": "ok", "/usr/bin/bar": "changed", "/usr/bin/baz": "error"}
+		}
+		if fmt.Sprint(status) != fmt.Sprint(expected) {
+			t.Errorf("MetaOnly %v: unexpected results %v, expected %v", metaonly, status, expected)
+		}
+	}
+}
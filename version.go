@@ -0,0 +1,165 @@
+package deb
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Version of a Debian package: [epoch:]upstream_version[-debian_revision]
+type Version struct {
+	epoch    int
+	upstream string
+	revision string
+}
+
+// ParseVersion parses version string as dpkg does
+func ParseVersion(data string) (*Version, error) {
+	v := new(Version)
+	data = strings.TrimSpace(data)
+	if data == "" {
+		return nil, fmt.Errorf("empty version")
+	}
+
+	if i := strings.Index(data, ":"); i > -1 {
+		epoch, err := strconv.Atoi(data[:i])
+		if err != nil || epoch < 0 {
+			return nil, fmt.Errorf("invalid epoch in version '%s'", data)
+		}
+		v.epoch = epoch
+		data = data[i+1:]
+	}
+	if i := strings.LastIndex(data, "-"); i > -1 {
+		v.revision = data[i+1:]
+		data = data[:i]
+	}
+	if data == "" {
+		return nil, fmt.Errorf("empty upstream version")
+	}
+	v.upstream = data
+
+	return v, nil
+}
+
+// Epoch of the version
+func (v *Version) Epoch() int {
+	return v.epoch
+}
+
+// Upstream part of the version
+func (v *Version) Upstream() string {
+	return v.upstream
+}
+
+// Revision returns Debian revision of the version
+func (v *Version) Revision() string {
+	return v.revision
+}
+
+func (v *Version) String() string {
+	s := v.upstream
+	if v.epoch > 0 {
+		s = strconv.Itoa(v.epoch) + ":" + s
+	}
+	if v.revision != "" {
+		s += "-" + v.revision
+	}
+	return s
+}
+
+// Compare returns -1, 0 or 1 if this version is lower, equal or greater than the other one.
+func (v *Version) Compare(other *Version) int {
+	if v.epoch != other.epoch {
+		return sign(v.epoch - other.epoch)
+	}
+	if r := verrevcmp(v.upstream, other.upstream); r != 0 {
+		return sign(r)
+	}
+	return sign(verrevcmp(v.revision, other.revision))
+}
+
+// CompareVersions compares two version strings. Unparsable versions are compared as plain strings.
+func CompareVersions(a string, b string) int {
+	va, erra := ParseVersion(a)
+	vb, errb := ParseVersion(b)
+	if erra != nil || errb != nil {
+		return strings.Compare(a, b)
+	}
+	return va.Compare(vb)
+}
+
+func sign(i int) int {
+	switch {
+	case i < 0:
+		return -1
+	case i > 0:
+		return 1
+	}
+	return 0
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+// Sorting weight of a character in non-digit parts: tilde sorts before anything,
+// even the end of the part, then letters, then everything else.
+func verOrder(c byte) int {
+	switch {
+	case isDigit(c):
+		return 0
+	case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z':
+		return int(c)
+	case c == '~':
+		return -1
+	case c != 0:
+		return int(c) + 256
+	}
+	return 0
+}
+
+// Compare version parts, same as verrevcmp in dpkg
+func verrevcmp(a string, b string) int {
+	at := func(s string, i int) byte {
+		if i < len(s) {
+			return s[i]
+		}
+		return 0
+	}
+
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		firstDiff := 0
+		for (i < len(a) && !isDigit(a[i])) || (j < len(b) && !isDigit(b[j])) {
+			ac, bc := verOrder(at(a, i)), verOrder(at(b, j))
+			if ac != bc {
+				return ac - bc
+			}
+			i++
+			j++
+		}
+		for at(a, i) == '0' {
+			i++
+		}
+		for at(b, j) == '0' {
+			j++
+		}
+		for isDigit(at(a, i)) && isDigit(at(b, j)) {
+			if firstDiff == 0 {
+				firstDiff = int(a[i]) - int(b[j])
+			}
+			i++
+			j++
+		}
+		if isDigit(at(a, i)) {
+			return 1
+		}
+		if isDigit(at(b, j)) {
+			return -1
+		}
+		if firstDiff != 0 {
+			return firstDiff
+		}
+	}
+	return 0
+}
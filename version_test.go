@@ -0,0 +1,85 @@
+package deb
+
+import "testing"
+
+// Expected results are as reported by "dpkg --compare-versions"
+func TestCompareVersions(t *testing.T) {
+	tests := []struct {
+		a, b     string
+		expected int
+	}{
+		{"1.0", "1.0", 0},
+		{"1.0", "1.0-0", 0},
+		{"1.0-1", "1.0-2", -1},
+		{"1.0", "1.0-1", -1},
+		{"1.0~rc1", "1.0", -1},
+		{"1.0~rc1", "1.0~rc2", -1},
+		{"1.0~~", "1.0~", -1},
+		{"1.0~", "1.0", -1},
+		{"1.0", "1.0+b1", -1},
+		{"1.0a", "1.0", 1},
+		{"1.0.1", "1.0a", 1},
+		{"1.10", "1.9", 1},
+		{"1.001", "1.1", 0},
+		{"1.01", "1.1", 0},
+		{"0:1.0", "1.0", 0},
+		{"1:0.1", "9.9", 1},
+		{"2:1.0", "1:9.9", 1},
+		{"1.0-1ubuntu1", "1.0-1", 1},
+		{"1.0-1ubuntu1", "1.0-1.1", -1},
+		{"2.34-0ubuntu3.2", "2.34-0ubuntu3.10", -1},
+		{"1:2.24-9~", "1:2.24-9", -1},
+		{"1.2.3-4+deb11u1", "1.2.3-4", 1},
+		{"1.0+dfsg-1", "1.0-1", 1},
+		{"1.0-1", "1.0+dfsg-1", -1},
+		{"1.0-a", "1.0-+", -1},
+		{"1.0.0", "1.0", 1},
+		{"7.6p2-4", "7.6-0", 1},
+		{"1.0-1~bpo11+1", "1.0-1", -1},
+		{"3.0-1", "3.0-1build1", -1},
+	}
+
+	for _, tt := range tests {
+		if got := CompareVersions(tt.a, tt.b); got != tt.expected {
+			t.Errorf("CompareVersions(%q, %q) = %d, expected %d", tt.a, tt.b, got, tt.expected)
+		}
+		if got := CompareVersions(tt.b, tt.a); got != -tt.expected {
+			t.Errorf("CompareVersions(%q, %q) = %d, expected %d", tt.b, tt.a, got, -tt.expected)
+		}
+	}
+}
+
+func TestParseVersion(t *testing.T) {
+	tests := []struct {
+		data     string
+		epoch    int
+		upstream string
+		revision string
+	}{
+		{"1.0", 0, "1.0", ""},
+		{"1:2.24-9~", 1, "2.24", "9~"},
+		{"2.34-0ubuntu3.2", 0, "2.34", "0ubuntu3.2"},
+		{"1.2-3-4", 0, "1.2-3", "4"},
+		{"3:1.0:2-1", 3, "1.0:2", "1"},
+	}
+
+	for _, tt := range tests {
+		v, err := ParseVersion(tt.data)
+		if err != nil {
+			t.Errorf("ParseVersion(%q) returned error: %v", tt.data, err)
+			continue
+		}
+		if v.Epoch() != tt.epoch || v.Upstream() != tt.upstream || v.Revision() != tt.revision {
+			t.Errorf("ParseVersion(%q) = %d %q %q", tt.data, v.Epoch(), v.Upstream(), v.Revision())
+		}
+		if v.String() != tt.data {
+			t.Errorf("ParseVersion(%q).String() = %q", tt.data, v.String())
+		}
+	}
+
+	for _, data := range []string{"", "x:1.0", "-1:1.0", "1:", "1:-1"} {
+		if _, err := ParseVersion(data); err == nil {
+			t.Errorf("ParseVersion(%q) expected to fail", data)
+		}
+	}
+}